@@ -0,0 +1,163 @@
+// admin 命令行管理工具，用于在没有管理员时创建第一个管理员等运维操作
+//
+//	go run ./cmd/admin create-admin -email admin@example.com -password secret123
+//	go run ./cmd/admin set-role -email someone@example.com -role moderator
+//	go run ./cmd/admin recount-posts
+//	go run ./cmd/admin reindex-posts
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/database"
+	"go-tree-hollow/pkg/segment"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	config := configs.LoadConfig()
+	db, err := database.NewDB(config.DatabaseDSN)
+	if err != nil {
+		log.Fatal("Failed to connect database:", err)
+	}
+
+	switch os.Args[1] {
+	case "create-admin":
+		err = createAdmin(db, os.Args[2:])
+	case "set-role":
+		err = setRole(db, os.Args[2:])
+	case "recount-posts":
+		err = recountPosts(db)
+	case "reindex-posts":
+		err = reindexPosts(db)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: admin <命令> [参数]
+
+命令:
+  create-admin -email <邮箱> -password <密码>   创建管理员账号
+  set-role -email <邮箱> -role <角色>           修改已有用户的角色（user / moderator / admin）
+  recount-posts                                根据点赞和评论明细重新计算帖子的点赞数和评论数
+  reindex-posts                                重新生成帖子的全文检索词元（修改分词规则或迁移后运行）`)
+}
+
+// createAdmin 创建管理员账号，邮箱视为已验证
+func createAdmin(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "管理员邮箱")
+	password := fs.String("password", "", "管理员密码（至少6位）")
+	fs.Parse(args)
+
+	if *email == "" || len(*password) < 6 {
+		fs.Usage()
+		return errors.New("需要提供邮箱和至少6位的密码")
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("email = ?", *email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("用户 %s 已存在，请使用 set-role 修改其角色", *email)
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           *email,
+		Password:        *password,
+		Role:            models.RoleAdmin,
+		EmailVerifiedAt: &now,
+	}
+	if err := db.Create(user).Error; err != nil {
+		return fmt.Errorf("创建管理员失败: %w", err)
+	}
+
+	log.Printf("已创建管理员 %s (id=%d)", user.Email, user.ID)
+	return nil
+}
+
+// setRole 修改已有用户的角色，新角色在用户下次登录或刷新令牌后生效
+func setRole(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "用户邮箱")
+	role := fs.String("role", "", "角色：user / moderator / admin")
+	fs.Parse(args)
+
+	if *email == "" || !models.IsAssignableRole(*role) {
+		fs.Usage()
+		return errors.New("需要提供邮箱和有效的角色")
+	}
+
+	result := db.Model(&models.User{}).Where("email = ?", *email).Update("role", *role)
+	if result.Error != nil {
+		return fmt.Errorf("修改角色失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("用户 %s 不存在", *email)
+	}
+
+	log.Printf("已将 %s 的角色设置为 %s", *email, *role)
+	return nil
+}
+
+// recountPosts 根据点赞和评论明细重新计算帖子的计数列，只更新与明细不一致的帖子
+func recountPosts(db *gorm.DB) error {
+	likes := "(SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id AND likes.deleted_at IS NULL)"
+	comments := "(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL)"
+
+	result := db.Exec("UPDATE posts SET likes_count = " + likes + ", comments_count = " + comments +
+		" WHERE likes_count <> " + likes + " OR comments_count <> " + comments)
+	if result.Error != nil {
+		return fmt.Errorf("重新计算帖子计数失败: %w", result.Error)
+	}
+
+	log.Printf("已修正 %d 个帖子的计数", result.RowsAffected)
+	return nil
+}
+
+// reindexPosts 按当前分词规则重新生成帖子的全文检索词元，只更新有变化的帖子
+func reindexPosts(db *gorm.DB) error {
+	var posts []models.Post
+	var updated int64
+	result := db.Unscoped().Select("id", "text_content", "search_tokens").FindInBatches(&posts, 500, func(tx *gorm.DB, batch int) error {
+		for _, post := range posts {
+			tokens := segment.Index(post.TextContent)
+			if tokens == post.SearchTokens {
+				continue
+			}
+			// UpdateColumn 不触发钩子，也不修改 updated_at
+			if err := db.Unscoped().Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("search_tokens", tokens).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return fmt.Errorf("重新生成检索词元失败: %w", result.Error)
+	}
+
+	log.Printf("已更新 %d 个帖子的检索词元", updated)
+	return nil
+}
@@ -4,28 +4,56 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	ServerPort    string
-	DatabaseDSN   string
-	JWTSecret     string
-	JWTExpireDays int
-	Email         EmailConfig
-	Code          CodeConfig
-	Redis         RedisConfig
+	AppName          string // 应用名称，用于邮件、两步验证等面向用户的场景
+	PublicURL        string // 服务对外访问的地址，用于生成邮件中的链接，如 https://hollow.example.com
+	ServerPort       string
+	DatabaseDSN      string
+	JWTKeysDir       string // 签名密钥目录，每个 <kid>.pem 文件为一把 Ed25519 密钥
+	JWTActiveKeyID   string // 当前用于签发令牌的密钥ID
+	JWTIssuer        string // 令牌签发方（iss）
+	JWTExpireDays    int    // 刷新令牌有效期（天）
+	JWTAccessMinutes int    // 访问令牌有效期（分钟）
+	AnonymousSecret  string // 生成匿名化名的密钥，泄露后可据此推算匿名作者
+	Email            EmailConfig
+	MailQueue        MailQueueConfig
+	Code             CodeConfig
+	KVBackend        string // 验证码、登录限制等临时数据的存储：redis / memory（仅当前进程有效，重启后清空）
+	Redis            RedisConfig
+	LoginGuard       LoginGuardConfig
+	Account          AccountConfig
+	Digest           DigestConfig
+	Trending         TrendingConfig
+	Guest            GuestConfig
+	OIDCProviders    []OIDCProviderConfig
 }
 
 type EmailConfig struct {
-	SMTPHost string
-	SMTPPort int
-	Username string
-	Password string // 使用授权码而非密码
-	From     string // 发件人格式： "昵称 <邮箱>"
-	Secure   bool   // 是否使用SSL/TLS
+	SMTPHost    string
+	SMTPPort    int
+	Username    string
+	Password    string // 使用授权码而非密码
+	From        string // 发件人格式： "昵称 <邮箱>"
+	Secure      bool   // 是否使用SSL/TLS
+	Transport   string // 发送方式：smtp / file（写入 OutboxDir 下的 .eml 文件）/ log（输出到日志）
+	OutboxDir   string // file 方式的邮件存放目录
+	TemplateDir string // 邮件模板目录，每种语言一个子目录
+}
+
+type MailQueueConfig struct {
+	Workers       int           // 并发发送的数量
+	MaxAttempts   int           // 最多发送次数，超过后移入失败列表不再重试
+	BaseBackoff   time.Duration // 首次重试的等待时长，之后每次失败翻倍
+	MaxBackoff    time.Duration // 重试等待时长上限
+	PollInterval  time.Duration // 检查到期邮件的间隔，新邮件入队时会立即发送
+	LockTimeout   time.Duration // 邮件被领取后超过该时长未完成，视为发送进程已退出并重新发送
+	DeadRetention time.Duration // 失败邮件的保留时长
 }
 
 type CodeConfig struct {
@@ -35,6 +63,56 @@ type CodeConfig struct {
 	MaxAttempts  int           // 最大尝试次数
 }
 
+type LoginGuardConfig struct {
+	MaxFailures     int           // 同一邮箱连续失败多少次后锁定账号
+	IPMaxFailures   int           // 同一IP在统计窗口内失败多少次后暂停其登录
+	DelayAfter      int           // 同一邮箱失败多少次后开始递增等待
+	DelayStep       time.Duration // 首次等待时长，之后每次失败翻倍
+	FailureWindow   time.Duration // 失败次数统计窗口
+	LockDuration    time.Duration // 首次锁定时长，重复锁定时翻倍
+	MaxLockDuration time.Duration // 锁定时长上限
+}
+
+type AccountConfig struct {
+	ExportDir           string        // 数据导出文件的存放目录（不对外公开，仅能通过下载接口获取）
+	ExportTTL           time.Duration // 导出文件的保留时长
+	DeletionGracePeriod time.Duration // 申请注销到实际删除之间的宽限期，期间可撤销
+	WorkerInterval      time.Duration // 后台任务检查间隔
+}
+
+type DigestConfig struct {
+	Secret         string        // 退订链接的签名密钥
+	Period         time.Duration // 动态摘要的发送周期
+	WorkerInterval time.Duration // 后台任务检查间隔
+}
+
+type TrendingConfig struct {
+	Windows             []TrendingWindow // 热门榜的统计窗口，第一个为默认窗口
+	WorkerInterval      time.Duration    // 增量更新有新互动的帖子热度的间隔
+	FullRefreshInterval time.Duration    // 全量重算窗口内所有帖子热度的间隔，用于反映取消点赞、删除评论等操作
+}
+
+// TrendingWindow 热门榜的一个统计窗口
+type TrendingWindow struct {
+	Name     string        // 窗口名称，即接口的 window 参数，如 24h、7d
+	Duration time.Duration // 只有该时长内发布的帖子参与排名
+	HalfLife time.Duration // 互动热度的半衰期，为窗口时长的四分之一
+}
+
+type GuestConfig struct {
+	PostTagIDs []uint // 游客可以发帖的话题，为空时游客不能发帖
+}
+
+// OIDCProviderConfig 一个 OIDC 登录提供方，端点通过 Issuer 的 discovery 文档自动获取
+type OIDCProviderConfig struct {
+	Name         string // 提供方标识，用于路由 /auth/oidc/:provider
+	Issuer       string // 如 https://sso.example.edu
+	ClientID     string
+	ClientSecret string   // 公共客户端可留空，仅依赖 PKCE
+	RedirectURL  string   // 在提供方登记的回调地址
+	Scopes       []string // 默认 openid email profile
+}
+
 type RedisConfig struct {
 	Addr         string        `mapstructure:"addr"`           // 地址: "localhost:6379"
 	Password     string        `mapstructure:"password"`       // 密码（默认为空）
@@ -55,17 +133,35 @@ func LoadConfig() *Config {
 	}
 	godotenv.Load(".env")
 	return &Config{
-		ServerPort:    getEnv("SERVER_PORT", "8081"),
-		DatabaseDSN:   getEnv("DATABASE_DSN", "test.db"),
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-		JWTExpireDays: getEnvAsInt("JWT_EXPIRE_DAYS", 7),
+		AppName:          getEnv("APP_NAME", "树洞"),
+		PublicURL:        strings.TrimSuffix(getEnv("PUBLIC_URL", "http://localhost:8081"), "/"),
+		ServerPort:       getEnv("SERVER_PORT", "8081"),
+		DatabaseDSN:      getEnv("DATABASE_DSN", "test.db"),
+		JWTKeysDir:       getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKeyID:   getEnv("JWT_ACTIVE_KID", ""),
+		JWTIssuer:        getEnv("JWT_ISSUER", "go-tree-hollow"),
+		JWTExpireDays:    getEnvAsInt("JWT_EXPIRE_DAYS", 7),
+		JWTAccessMinutes: getEnvAsInt("JWT_ACCESS_EXPIRE_MINUTES", 15),
+		AnonymousSecret:  getEnv("ANONYMOUS_SECRET", ""),
 		Email: EmailConfig{
-			SMTPHost: getEnv("EMAIL_SMTP_HOST", "smtp.gmail.com"),
-			SMTPPort: getEnvAsInt("EMAIL_SMTP_PORT", 587),
-			Username: getEnv("EMAIL_USERNAME", "your-email@qq.com"),
-			Password: getEnv("EMAIL_PASSWORD", "your-email-password"),
-			From:     getEnv("EMAIL_FROM", "Your Name <your-email@qq.com>"),
-			Secure:   true,
+			SMTPHost:    getEnv("EMAIL_SMTP_HOST", "smtp.gmail.com"),
+			SMTPPort:    getEnvAsInt("EMAIL_SMTP_PORT", 587),
+			Username:    getEnv("EMAIL_USERNAME", "your-email@qq.com"),
+			Password:    getEnv("EMAIL_PASSWORD", "your-email-password"),
+			From:        getEnv("EMAIL_FROM", "Your Name <your-email@qq.com>"),
+			Secure:      true,
+			Transport:   getEnv("EMAIL_TRANSPORT", "smtp"),
+			OutboxDir:   getEnv("EMAIL_OUTBOX_DIR", "outbox"),
+			TemplateDir: getEnv("EMAIL_TEMPLATE_DIR", "templates/email"),
+		},
+		MailQueue: MailQueueConfig{
+			Workers:       getEnvAsInt("EMAIL_QUEUE_WORKERS", 2),
+			MaxAttempts:   getEnvAsInt("EMAIL_MAX_ATTEMPTS", 8),
+			BaseBackoff:   30 * time.Second,
+			MaxBackoff:    2 * time.Hour,
+			PollInterval:  5 * time.Second,
+			LockTimeout:   5 * time.Minute,
+			DeadRetention: 30 * 24 * time.Hour,
 		},
 		Code: CodeConfig{
 			Length:       6,
@@ -73,6 +169,7 @@ func LoadConfig() *Config {
 			SendInterval: 1 * time.Minute,
 			MaxAttempts:  5,
 		},
+		KVBackend: getEnv("KV_BACKEND", "redis"),
 		Redis: RedisConfig{
 			Addr:         getEnv("REDIS_ADDR", "localhost:6379"),
 			Password:     getEnv("REDIS_PASSWORD", "secret"),
@@ -84,7 +181,89 @@ func LoadConfig() *Config {
 			ReadTimeout:  3 * time.Second,
 			WriteTimeout: 3 * time.Second,
 		},
+		LoginGuard: LoginGuardConfig{
+			MaxFailures:     getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+			IPMaxFailures:   getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+			DelayAfter:      3,
+			DelayStep:       2 * time.Second,
+			FailureWindow:   15 * time.Minute,
+			LockDuration:    15 * time.Minute,
+			MaxLockDuration: 24 * time.Hour,
+		},
+		Account: AccountConfig{
+			ExportDir:           getEnv("EXPORT_DIR", "exports"),
+			ExportTTL:           7 * 24 * time.Hour,
+			DeletionGracePeriod: time.Duration(getEnvAsInt("ACCOUNT_DELETION_GRACE_DAYS", 14)) * 24 * time.Hour,
+			WorkerInterval:      10 * time.Minute,
+		},
+		Digest: DigestConfig{
+			Secret:         getEnv("DIGEST_SECRET", ""),
+			Period:         7 * 24 * time.Hour,
+			WorkerInterval: time.Hour,
+		},
+		Trending: TrendingConfig{
+			Windows:             loadTrendingWindows(),
+			WorkerInterval:      time.Minute,
+			FullRefreshInterval: time.Hour,
+		},
+		Guest: GuestConfig{
+			PostTagIDs: getEnvAsUintList("GUEST_POST_TAG_IDS"),
+		},
+		OIDCProviders: loadOIDCProviders(),
+	}
+}
+
+// loadOIDCProviders 读取 OIDC_PROVIDERS 列出的提供方，
+// 每个提供方的配置使用 OIDC_<NAME>_ 前缀，如 OIDC_SCHOOL_ISSUER
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			log.Printf("OIDC 提供方 %s 缺少 ISSUER、CLIENT_ID 或 REDIRECT_URL 配置，已忽略", name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// loadTrendingWindows 读取 TRENDING_WINDOWS 列出的热门榜窗口，如 "24h,7d"，
+// 支持 Go 的时长格式以及以 d 结尾的天数，无法解析的项被忽略
+func loadTrendingWindows() []TrendingWindow {
+	var windows []TrendingWindow
+	for _, name := range strings.Split(getEnv("TRENDING_WINDOWS", "24h,7d"), ",") {
+		name = strings.TrimSpace(name)
+		var duration time.Duration
+		if days, ok := strings.CutSuffix(name, "d"); ok {
+			if n, err := strconv.Atoi(days); err == nil {
+				duration = time.Duration(n) * 24 * time.Hour
+			}
+		} else if d, err := time.ParseDuration(name); err == nil {
+			duration = d
+		}
+		if duration <= 0 {
+			log.Printf("无法解析热门榜窗口 %q，已忽略", name)
+			continue
+		}
+		windows = append(windows, TrendingWindow{Name: name, Duration: duration, HalfLife: duration / 4})
 	}
+	if len(windows) == 0 {
+		windows = []TrendingWindow{{Name: "24h", Duration: 24 * time.Hour, HalfLife: 6 * time.Hour}}
+	}
+	return windows
 }
 
 func getEnv(key, defaultValue string) string {
@@ -101,3 +280,14 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsUintList 读取逗号分隔的数字列表，忽略无法解析的项
+func getEnvAsUintList(key string) []uint {
+	var values []uint
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if value, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64); err == nil {
+			values = append(values, uint(value))
+		}
+	}
+	return values
+}
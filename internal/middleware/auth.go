@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"go-tree-hollow/pkg/utils"
 	"net/http"
 	"strings"
@@ -8,8 +9,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// TokenChecker 在签名校验通过后检查令牌在服务端的状态
+type TokenChecker interface {
+	// IsRevoked 判断令牌是否已被吊销或其所属会话是否已失效
+	IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error)
+	// Touch 记录令牌所属会话的最近活跃时间
+	Touch(ctx context.Context, claims *utils.Claims, ip string)
+}
+
 // AuthRequired JWT认证中间件
-func AuthRequired() gin.HandlerFunc {
+func AuthRequired(checker TokenChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -32,15 +41,40 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// 游客令牌只能在签发时绑定的设备上使用
+		if !deviceMatches(c, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is bound to another device"})
+			c.Abort()
+			return
+		}
+
+		// 检查令牌是否已被吊销（退出登录、修改密码、设备被移除等）
+		if checker != nil {
+			revoked, err := checker.IsRevoked(c.Request.Context(), claims)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+			checker.Touch(c.Request.Context(), claims, c.ClientIP())
+		}
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
 
 // OptionalAuth 尝试获取用户信息，但不强制认证
-func OptionalAuth() gin.HandlerFunc {
+func OptionalAuth(checker TokenChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,14 +89,33 @@ func OptionalAuth() gin.HandlerFunc {
 		}
 
 		claims, err := utils.ParseToken(parts[1])
-		if err != nil {
+		if err != nil || !deviceMatches(c, claims) {
 			c.Next()
 			return
 		}
 
+		// 已吊销的令牌按未登录处理
+		if checker != nil {
+			if revoked, err := checker.IsRevoked(c.Request.Context(), claims); err != nil || revoked {
+				c.Next()
+				return
+			}
+		}
+
 		// 将用户信息存入上下文，但不中断请求
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
+
+// deviceMatches 检查绑定设备的令牌是否来自该设备，未绑定设备的令牌直接通过
+func deviceMatches(c *gin.Context, claims *utils.Claims) bool {
+	if claims.DeviceID == "" {
+		return true
+	}
+	deviceID := c.GetHeader("X-Device-ID")
+	return deviceID != "" && utils.DeviceFingerprint(deviceID) == claims.DeviceID
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-tree-hollow/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 要求当前用户的角色不低于 role，需在 AuthRequired 之后使用
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.RoleAtLeast(c.GetString("role"), role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission 要求当前用户的角色拥有指定权限，需在 AuthRequired 之后使用
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.HasPermission(c.GetString("role"), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
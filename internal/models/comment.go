@@ -5,9 +5,10 @@ import "gorm.io/gorm"
 // Comment represents a comment on a post
 type Comment struct {
 	gorm.Model
-	UserID  uint   `json:"user_id" gorm:"not null;index"`
-	User    User   `json:"user" gorm:"foreignKey:UserID"`
-	PostID  uint   `json:"post_id" gorm:"not null;index"`
-	Post    Post   `json:"post" gorm:"foreignKey:PostID"`
-	Content string `json:"content" gorm:"type:text;not null"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	PostID    uint       `json:"post_id" gorm:"not null;index"`
+	Post      Post       `json:"post" gorm:"foreignKey:PostID"`
+	Content   string     `json:"content" gorm:"type:text;not null"`
+	Pseudonym *Pseudonym `json:"pseudonym,omitempty" gorm:"-"` // 匿名帖子下评论者的化名
 }
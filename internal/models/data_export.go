@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 数据导出任务状态
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusReady      = "ready"
+	ExportStatusFailed     = "failed"
+)
+
+// DataExport 用户数据导出任务，完成后生成的 ZIP 文件在 ExpiresAt 之前可供下载
+type DataExport struct {
+	gorm.Model
+	UserID      uint       `gorm:"not null;index" json:"-"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	FilePath    string     `gorm:"type:varchar(1024)" json:"-"`
+	Error       string     `gorm:"type:varchar(255)" json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
@@ -5,9 +5,9 @@ import "gorm.io/gorm"
 // Like represents a like on a post by a user
 type Like struct {
 	gorm.Model
-	UserID uint  `json:"user_id" gorm:"not null;index"`
+	UserID uint  `json:"user_id" gorm:"not null;index;uniqueIndex:idx_likes_user_post"`
 	User   User  `json:"user" gorm:"foreignKey:UserID"`
-	PostID uint  `json:"post_id" gorm:"not null;index"`
+	PostID uint  `json:"post_id" gorm:"not null;index;uniqueIndex:idx_likes_user_post"`
 	Post   Post  `json:"post" gorm:"foreignKey:PostID"`
 }
 
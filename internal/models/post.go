@@ -1,22 +1,47 @@
 package models
 
 import (
+	"go-tree-hollow/pkg/segment"
+
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+// 帖子状态，只有已发布的帖子会出现在搜索结果中
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+)
+
 // Post represents the canned content created by a user.
 type Post struct {
 	gorm.Model
-	UserID      uint           `json:"user_id" gorm:"not null;index"`
-	User        User           `json:"user" gorm:"foreignKey:UserID"`
-	Type        string         `json:"type" gorm:"not null;index"` // e.g., "text_image", "video", "audio", "live_photo"
-	TextContent string         `json:"text_content,omitempty" gorm:"type:text"`
-	MediaURLs   datatypes.JSON `json:"media_urls,omitempty" gorm:"type:json"`
-	CoverURL    string         `json:"cover_url,omitempty" gorm:"type:varchar(1024)"`
-	Status      string         `json:"status" gorm:"not null;default:'draft';index"` // "draft", "published"
-	TagID       *uint          `json:"tag_id,omitempty" gorm:"index"`
-	Tag         *Tag           `json:"tag,omitempty" gorm:"foreignKey:TagID"`
-	LikesCount  int64          `json:"likes_count" gorm:"-"`
-	IsLiked     bool           `json:"is_liked" gorm:"-"`
+	UserID        uint           `json:"user_id" gorm:"not null;index"`
+	User          *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Anonymous     bool           `json:"anonymous" gorm:"not null;default:false"` // 匿名发布，公开响应中以化名代替作者
+	Pseudonym     *Pseudonym     `json:"pseudonym,omitempty" gorm:"-"`
+	Type          string         `json:"type" gorm:"not null;index"` // e.g., "text_image", "video", "audio", "live_photo"
+	TextContent   string         `json:"text_content,omitempty" gorm:"type:text"`
+	MediaURLs     datatypes.JSON `json:"media_urls,omitempty" gorm:"type:json"`
+	CoverURL      string         `json:"cover_url,omitempty" gorm:"type:varchar(1024)"`
+	Status        string         `json:"status" gorm:"not null;default:'draft';index"` // "draft", "published"
+	TagID         *uint          `json:"tag_id,omitempty" gorm:"index"`
+	Tag           *Tag           `json:"tag,omitempty" gorm:"foreignKey:TagID"`
+	LikesCount    int64          `json:"likes_count" gorm:"not null;default:0"`    // 随点赞在同一事务中更新
+	CommentsCount int64          `json:"comments_count" gorm:"not null;default:0"` // 随评论在同一事务中更新
+	IsLiked       bool           `json:"is_liked" gorm:"-"`
+	SearchTokens  string         `json:"-" gorm:"type:text;not null;default:''"` // 正文切分后的词元，供全文检索索引使用
+}
+
+// BeforeSave 钩子：根据正文生成全文检索词元
+func (p *Post) BeforeSave(tx *gorm.DB) error {
+	p.SearchTokens = segment.Index(p.TextContent)
+	return nil
+}
+
+// Pseudonym is the generated identity shown in place of the author in an anonymous thread.
+type Pseudonym struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	IsAuthor  bool   `json:"is_author"` // 是否为帖子作者（楼主）
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PostScore 帖子在某个热门榜窗口内的热度，由后台任务增量更新，热门榜接口直接按分数排序读取
+type PostScore struct {
+	PostID    uint    `gorm:"primaryKey"`
+	Period    string  `gorm:"primaryKey;type:varchar(16)"` // 热门榜窗口名称，如 24h、7d
+	Score     float64 `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 待发送邮件的状态，发送成功的邮件直接从队列中删除
+const (
+	QueuedEmailPending = "pending" // 等待发送或等待重试
+	QueuedEmailSending = "sending" // 已被发送进程领取
+	QueuedEmailDead    = "dead"    // 多次发送失败，不再重试
+)
+
+// QueuedEmail 发送队列中的一封邮件
+type QueuedEmail struct {
+	gorm.Model
+	From          string     `gorm:"type:varchar(255);not null" json:"from"`
+	To            string     `gorm:"type:varchar(255);not null" json:"to"`
+	Subject       string     `gorm:"type:varchar(255);not null" json:"subject"`
+	HTMLBody      string     `gorm:"type:text" json:"-"`
+	TextBody      string     `gorm:"type:text" json:"-"`
+	Headers       string     `gorm:"type:text" json:"-"` // 额外的邮件头（JSON 对象），为空表示没有
+	Status        string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LockedAt      *time.Time `json:"-"` // 被领取的时间，发送进程异常退出后超时的邮件会被重新领取
+	LastError     string     `gorm:"type:varchar(1024)" json:"last_error,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// 用户角色，权限由低到高
+const (
+	RoleGuest     = "guest" // 未注册的设备账号，注册后升级为 user
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission 受保护操作所需的权限
+type Permission string
+
+const (
+	PermModerateContent Permission = "content:moderate" // 处理他人的帖子和评论
+	PermManageRoles     Permission = "roles:manage"     // 修改用户角色
+	PermManageMail      Permission = "mail:manage"      // 查看邮件发送队列、重发失败的邮件
+	PermUseChat         Permission = "chat:use"         // 使用私信
+	PermPostAnyTag      Permission = "posts:any_tag"    // 在任意话题下发帖，游客只能在指定话题下发帖
+)
+
+// roleRanks 角色等级，高等级角色拥有低等级角色的全部权限
+var roleRanks = map[string]int{
+	RoleGuest:     0,
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// rolePermissions 每个角色在低等级角色之外额外拥有的权限
+var rolePermissions = map[string][]Permission{
+	RoleUser:      {PermUseChat, PermPostAnyTag},
+	RoleModerator: {PermModerateContent},
+	RoleAdmin:     {PermManageRoles, PermManageMail},
+}
+
+// IsValidRole 判断角色名是否有效
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// IsAssignableRole 判断角色能否由管理员分配，游客角色只能通过游客登录获得
+func IsAssignableRole(role string) bool {
+	return role != RoleGuest && IsValidRole(role)
+}
+
+// RoleAtLeast 判断 role 的等级是否不低于 required
+func RoleAtLeast(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// HasPermission 判断角色是否拥有指定权限（含低等级角色的权限）
+func HasPermission(role string, perm Permission) bool {
+	for r, perms := range rolePermissions {
+		if !RoleAtLeast(role, r) {
+			continue
+		}
+		for _, p := range perms {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session 登录会话，每次登录创建一个，对应一台设备
+// 同一会话内轮换出的刷新令牌和访问令牌都携带该会话ID
+type Session struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	UserAgent  string     `gorm:"type:varchar(512)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(64)" json:"ip"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsActive 判断会话是否仍然有效
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil
+}
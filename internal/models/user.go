@@ -1,25 +1,68 @@
 package models
 
 import (
+	"time"
+
 	"go-tree-hollow/pkg/utils"
 
 	"gorm.io/gorm"
 )
 
+// GuestEmailDomain 游客账号占位邮箱使用的域名（.invalid 为保留顶级域名，不会收到邮件）
+const GuestEmailDomain = "guest.invalid"
+
 type User struct {
 	gorm.Model
-	Email         string `gorm:"uniqueIndex;not null" json:"email"`
-	Password      string `gorm:"not null" json:"-"`
-	Nickname      string `gorm:"type:varchar(50)" json:"nickname"`
-	AvatarURL     string `gorm:"type:varchar(1024)" json:"avatar_url"`
-	BackgroundURL string `gorm:"type:varchar(1024)" json:"background_url"`
-	Birthday      string `gorm:"type:varchar(20)" json:"birthday"` // YYYY-MM-DD
-	Bio           string `gorm:"type:varchar(255)" json:"bio"`
-	Location      string `gorm:"type:varchar(100)" json:"location"`
+	Email           string     `gorm:"uniqueIndex;not null" json:"email,omitempty"`
+	Password        string     `gorm:"not null" json:"-"`
+	Nickname        string     `gorm:"type:varchar(50)" json:"nickname"`
+	AvatarURL       string     `gorm:"type:varchar(1024)" json:"avatar_url"`
+	BackgroundURL   string     `gorm:"type:varchar(1024)" json:"background_url"`
+	Birthday        string     `gorm:"type:varchar(20)" json:"birthday"` // YYYY-MM-DD
+	Bio             string     `gorm:"type:varchar(255)" json:"bio"`
+	Location        string     `gorm:"type:varchar(100)" json:"location"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // 邮箱验证通过的时间，为空表示尚未验证
+	Role            string     `gorm:"type:varchar(20);not null;default:'user'" json:"role,omitempty"`
+	Locale          string     `gorm:"type:varchar(10)" json:"locale"` // 邮件等通知使用的语言，如 zh-CN、en
+
+	// 每周动态摘要：是否已退订，以及上一次摘要统计到的时间
+	DigestOptOut bool       `gorm:"not null;default:false" json:"-"`
+	DigestSentAt *time.Time `json:"-"`
+
+	// 游客账号绑定的设备标识哈希，注册升级后清空
+	GuestDeviceHash *string `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+
+	// 申请注销后到期执行删除的时间，为空表示未申请注销
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"`
+
+	// 两步验证（TOTP），均不对外输出
+	TOTPSecret        string     `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabledAt     *time.Time `json:"-"`
+	TOTPLastStep      int64      `gorm:"not null;default:0" json:"-"` // 最近一次使用的时间步，防止验证码重放
+	TOTPRecoveryCodes string     `gorm:"type:text" json:"-"`          // 恢复码的 bcrypt 哈希（JSON 数组）
+}
+
+// IsEmailVerified 判断用户邮箱是否已验证
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsGuest 判断是否为尚未注册的游客账号
+func (u *User) IsGuest() bool {
+	return u.Role == RoleGuest
 }
 
-// BeforeCreate 钩子：自动加密密码
+// IsTwoFactorEnabled 判断用户是否开启了两步验证
+func (u *User) IsTwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// BeforeCreate 钩子：设置默认角色并自动加密密码
 func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+
 	hashedPassword, err := utils.HashPassword(u.Password)
 	if err != nil {
 		return err
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity 用户绑定的第三方登录身份（OIDC），同一提供方的 subject 只能绑定一个用户
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Provider string `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject  string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	Email    string `gorm:"type:varchar(255)" json:"email"` // 绑定时提供方返回的邮箱
+}
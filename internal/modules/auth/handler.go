@@ -1,7 +1,14 @@
 package auth
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
+	"go-tree-hollow/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,11 +28,20 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.Register(&req)
+	// 游客登录状态下注册时，直接升级当前游客账号
+	var guestID uint
+	if c.GetString("role") == models.RoleGuest {
+		guestID = c.GetUint("userID")
+	}
+
+	user, err := h.service.Register(c.Request.Context(), &req, guestID)
 	if err != nil {
 		status := http.StatusInternalServerError
-		if err.Error() == "用户已存在" {
+		switch {
+		case errors.Is(err, ErrUserExists), errors.Is(err, ErrGuestUpgraded):
 			status = http.StatusConflict
+		case errors.Is(err, email.ErrCodeExpired), errors.Is(err, email.ErrCodeInvalid), errors.Is(err, email.ErrCodeAttemptsExceeded):
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
@@ -37,6 +53,30 @@ func (h *Handler) Register(c *gin.Context) {
 			"id":    user.ID,
 			"email": user.Email,
 		},
+		"upgraded": guestID != 0, // 为 true 时游客令牌已失效，需使用邮箱重新登录
+	})
+}
+
+// GuestLogin 游客登录，之后的请求需在 X-Device-ID 请求头中携带相同的设备标识
+func (h *Handler) GuestLogin(c *gin.Context) {
+	var req GuestLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, created, err := h.service.LoginAsGuest(&req, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "登录成功",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"created":       created,
 	})
 }
 
@@ -47,14 +87,401 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.Login(&req)
+	result, err := h.service.Login(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			respondLoginBlocked(c, blocked)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	if result.MFAToken != "" {
+		respondMFARequired(c, result.MFAToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "登录成功",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+	})
+}
+
+func (h *Handler) LoginWithCode(c *gin.Context) {
+	var req CodeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, created, err := h.service.LoginWithCode(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, email.ErrCodeExpired) || errors.Is(err, email.ErrCodeInvalid) || errors.Is(err, email.ErrCodeAttemptsExceeded) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if result.MFAToken != "" {
+		respondMFARequired(c, result.MFAToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "登录成功",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"created":       created,
+	})
+}
+
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.LoginTwoFactor(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			respondLoginBlocked(c, blocked)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrTwoFactorCodeInvalid) || errors.Is(err, ErrMFATokenInvalid) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "登录成功",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func (h *Handler) OIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.OIDCProviders()})
+}
+
+// OIDCLogin 跳转到第三方登录页
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	authURL, err := h.service.OIDCAuthURL(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 第三方登录回调，完成后返回与密码登录相同的令牌
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": ErrOIDCLoginFailed.Error(),
+			"code":  errCode,
+		})
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 code 或 state 参数"})
+		return
+	}
+
+	result, created, err := h.service.LoginWithOIDC(c.Request.Context(), c.Param("provider"), code, state, clientInfo(c))
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if result.MFAToken != "" {
+		respondMFARequired(c, result.MFAToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "登录成功",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"created":       created,
+	})
+}
+
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	setup, err := h.service.SetupTwoFactor(userID.(uint))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.EnableTwoFactor(userID.(uint), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrTwoFactorAlreadyEnabled):
+			status = http.StatusConflict
+		case errors.Is(err, ErrTwoFactorNotSetup), errors.Is(err, ErrTwoFactorCodeInvalid):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已开启，请妥善保存恢复码",
+		"recovery_codes": codes,
+	})
+}
+
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(c.Request.Context(), userID.(uint), &req, clientInfo(c)); err != nil {
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			respondLoginBlocked(c, blocked)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrTwoFactorNotEnabled) || errors.Is(err, ErrTwoFactorCodeInvalid) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ForgotPassword(c.Request.Context(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，重置验证码将发送至邮箱"})
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), &req); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, email.ErrCodeExpired) || errors.Is(err, email.ErrCodeInvalid) || errors.Is(err, email.ErrCodeAttemptsExceeded) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
+
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrRefreshTokenInvalid) || errors.Is(err, ErrRefreshTokenReused) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"token":   token,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req LogoutRequest
+	// 请求体可选，未提供时只退出当前设备
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.service.Logout(c.Request.Context(), claims.(*utils.Claims), &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+func (h *Handler) ListSessions(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	current := claims.(*utils.Claims)
+
+	sessions, err := h.service.ListSessions(current.UserID, current.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID.(uint), uint(sessionID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "该设备已退出登录"})
+}
+
+// JWKS 发布用于验证访问令牌的公钥集合
+func (h *Handler) JWKS(c *gin.Context) {
+	set, err := utils.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签名密钥不可用"})
+		return
+	}
+
+	// 允许验证方短时间缓存，密钥轮换时新公钥需提前发布
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
+// clientInfo 从请求中提取记录到会话的客户端信息
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// oidcErrorStatus 将第三方登录的错误映射为HTTP状态码
+func oidcErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrOIDCProviderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrOIDCStateInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrOIDCEmailUnverified):
+		return http.StatusForbidden
+	case errors.Is(err, ErrOIDCLoginFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondMFARequired 返回需要两步验证的响应，客户端凭 mfa_token 调用 /auth/login/2fa
+func respondMFARequired(c *gin.Context, mfaToken string) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "请输入两步验证码",
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+	})
+}
+
+// respondLoginBlocked 返回登录受限的响应，code 字段供客户端区分锁定与普通失败
+func respondLoginBlocked(c *gin.Context, err *LoginBlockedError) {
+	status, code := http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS"
+	if errors.Is(err, ErrAccountLocked) {
+		status, code = http.StatusLocked, "ACCOUNT_LOCKED"
+	}
+	retryAfter := int64(math.Ceil(err.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(status, gin.H{
+		"error":       err.Error(),
+		"code":        code,
+		"retry_after": retryAfter,
 	})
 }
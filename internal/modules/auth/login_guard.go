@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/pkg/kv"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("邮箱或密码错误")
+	ErrAccountLocked      = errors.New("登录失败次数过多，账号已被临时锁定")
+	ErrTooManyAttempts    = errors.New("登录尝试过于频繁，请稍后再试")
+)
+
+// LoginBlockedError 登录被限制时返回，RetryAfter 为客户端需要等待的时间
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
+// LoginGuard 基于键值存储记录登录失败次数，按邮箱和客户端IP分别限制暴力破解
+//
+// 同一邮箱失败 DelayAfter 次后，每次失败都需要等待递增的时间才能再次尝试；
+// 失败 MaxFailures 次后账号被锁定，重复锁定的时长逐次翻倍。
+// 同一IP失败过多时暂停该IP的所有登录尝试。
+type LoginGuard struct {
+	store  kv.Store
+	prefix string
+	cfg    configs.LoginGuardConfig
+}
+
+func NewLoginGuard(store kv.Store, prefix string, cfg configs.LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		store:  store,
+		prefix: prefix,
+		cfg:    cfg,
+	}
+}
+
+// Check 在校验密码前调用，当前邮箱或IP被限制时返回 *LoginBlockedError
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+
+	if ttl := g.ttl(ctx, g.buildKey("lock", email)); ttl > 0 {
+		return &LoginBlockedError{Err: ErrAccountLocked, RetryAfter: ttl}
+	}
+	if ttl := g.ttl(ctx, g.buildKey("ip_lock", ip)); ttl > 0 {
+		return &LoginBlockedError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+	}
+	if ttl := g.ttl(ctx, g.buildKey("delay", email)); ttl > 0 {
+		return &LoginBlockedError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败的登录，返回本次失败是否导致账号被锁定
+func (g *LoginGuard) RecordFailure(ctx context.Context, email, ip string) (bool, error) {
+	email = normalizeEmail(email)
+
+	ipFailures, err := g.incr(ctx, g.buildKey("ip_fail", ip), g.cfg.FailureWindow)
+	if err != nil {
+		return false, err
+	}
+	if ipFailures >= int64(g.cfg.IPMaxFailures) {
+		g.store.Set(ctx, g.buildKey("ip_lock", ip), "1", g.cfg.LockDuration)
+		g.store.Del(ctx, g.buildKey("ip_fail", ip))
+	}
+
+	failures, err := g.incr(ctx, g.buildKey("fail", email), g.cfg.FailureWindow)
+	if err != nil {
+		return false, err
+	}
+
+	if failures >= int64(g.cfg.MaxFailures) {
+		// 锁定次数保留一天，用于计算递增的锁定时长
+		locks, err := g.incr(ctx, g.buildKey("locks", email), 24*time.Hour)
+		if err != nil {
+			return false, err
+		}
+		duration := backoff(g.cfg.LockDuration, locks-1, g.cfg.MaxLockDuration)
+		if err := g.store.Set(ctx, g.buildKey("lock", email), "1", duration); err != nil {
+			return false, err
+		}
+		g.store.Del(ctx, g.buildKey("fail", email), g.buildKey("delay", email))
+		return true, nil
+	}
+
+	if failures >= int64(g.cfg.DelayAfter) {
+		delay := backoff(g.cfg.DelayStep, failures-int64(g.cfg.DelayAfter), g.cfg.LockDuration)
+		g.store.Set(ctx, g.buildKey("delay", email), "1", delay)
+	}
+	return false, nil
+}
+
+// Reset 登录成功后清除该邮箱的失败记录
+func (g *LoginGuard) Reset(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	_, err := g.store.Del(ctx,
+		g.buildKey("fail", email),
+		g.buildKey("delay", email),
+		g.buildKey("locks", email),
+	)
+	return err
+}
+
+// incr 自增计数器，首次创建时设置过期时间
+func (g *LoginGuard) incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	n, err := g.store.Incr(ctx, key, window)
+	if err != nil {
+		return 0, fmt.Errorf("记录登录失败次数失败: %w", err)
+	}
+	return n, nil
+}
+
+func (g *LoginGuard) ttl(ctx context.Context, key string) time.Duration {
+	ttl, err := g.store.TTL(ctx, key)
+	if err != nil {
+		return 0
+	}
+	return ttl
+}
+
+func (g *LoginGuard) buildKey(kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", g.prefix, kind, id)
+}
+
+// backoff 计算 base * 2^n，结果不超过 max
+func backoff(base time.Duration, n int64, max time.Duration) time.Duration {
+	d := base
+	for i := int64(0); i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/pkg/kv"
+)
+
+var testGuardConfig = configs.LoginGuardConfig{
+	MaxFailures:     5,
+	IPMaxFailures:   20,
+	DelayAfter:      3,
+	DelayStep:       time.Second,
+	FailureWindow:   15 * time.Minute,
+	LockDuration:    15 * time.Minute,
+	MaxLockDuration: time.Hour,
+}
+
+func TestLoginGuardEmailThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		wantErr    error // 最后一次失败之后 Check 的结果
+		wantLocked bool  // 最后一次失败是否导致锁定
+		wantRetry  time.Duration
+	}{
+		{"未达到等待阈值", 2, nil, false, 0},
+		{"达到等待阈值", 3, ErrTooManyAttempts, false, time.Second},
+		{"等待时间翻倍", 4, ErrTooManyAttempts, false, 2 * time.Second},
+		{"达到锁定阈值", 5, ErrAccountLocked, true, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			guard := NewLoginGuard(kv.NewMemoryStore(), "test", testGuardConfig)
+
+			var locked bool
+			for i := 0; i < tt.failures; i++ {
+				var err error
+				if locked, err = guard.RecordFailure(ctx, "User@Example.com", "10.0.0.1"); err != nil {
+					t.Fatalf("RecordFailure: %v", err)
+				}
+			}
+			if locked != tt.wantLocked {
+				t.Errorf("locked = %v, want %v", locked, tt.wantLocked)
+			}
+
+			// 邮箱不区分大小写
+			err := guard.Check(ctx, " user@example.com", "10.0.0.2")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check error = %v, want %v", err, tt.wantErr)
+			}
+			var blocked *LoginBlockedError
+			if errors.As(err, &blocked) && (blocked.RetryAfter <= tt.wantRetry-time.Second || blocked.RetryAfter > tt.wantRetry) {
+				t.Errorf("RetryAfter = %v, want about %v", blocked.RetryAfter, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestLoginGuardIPThreshold(t *testing.T) {
+	ctx := context.Background()
+	cfg := testGuardConfig
+	cfg.IPMaxFailures = 3
+	guard := NewLoginGuard(kv.NewMemoryStore(), "test", cfg)
+
+	// 每次使用不同的邮箱，只有 IP 的失败次数累计
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	for i, email := range emails {
+		if err := guard.Check(ctx, "other@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Check before failure %d: %v", i+1, err)
+		}
+		if _, err := guard.RecordFailure(ctx, email, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+
+	if err := guard.Check(ctx, "other@example.com", "10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Check from blocked IP error = %v, want ErrTooManyAttempts", err)
+	}
+	if err := guard.Check(ctx, "other@example.com", "10.0.0.2"); err != nil {
+		t.Errorf("Check from another IP error = %v, want nil", err)
+	}
+}
+
+func TestLoginGuardReset(t *testing.T) {
+	ctx := context.Background()
+	guard := NewLoginGuard(kv.NewMemoryStore(), "test", testGuardConfig)
+
+	for i := 0; i < testGuardConfig.DelayAfter; i++ {
+		guard.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	}
+	if err := guard.Reset(ctx, "USER@example.com"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := guard.Check(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("Check after reset error = %v, want nil", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		base time.Duration
+		n    int64
+		max  time.Duration
+		want time.Duration
+	}{
+		{time.Second, 0, time.Minute, time.Second},
+		{time.Second, 1, time.Minute, 2 * time.Second},
+		{time.Second, 5, time.Minute, 32 * time.Second},
+		{time.Second, 6, time.Minute, time.Minute},
+		{time.Second, 100, time.Minute, time.Minute},
+		{2 * time.Minute, 0, time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.base, tt.n, tt.max); got != tt.want {
+			t.Errorf("backoff(%v, %d, %v) = %v, want %v", tt.base, tt.n, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/kv"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("不支持的登录方式")
+	ErrOIDCStateInvalid     = errors.New("登录请求已过期，请重新发起")
+	ErrOIDCEmailUnverified  = errors.New("第三方账号未提供已验证的邮箱，无法登录")
+	ErrOIDCLoginFailed      = errors.New("第三方登录失败")
+)
+
+// oidcStateTTL 从跳转到提供方到回调完成的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState 发起登录时保存的状态，回调时凭 state 取回并立即删除
+type oidcState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// OIDCService 管理 OIDC 提供方，并将第三方身份关联到本站用户
+type OIDCService struct {
+	repo      *Repository
+	tokens    *TokenService
+	store     kv.Store
+	prefix    string
+	providers map[string]*oidcProvider
+}
+
+func NewOIDCService(repo *Repository, tokens *TokenService, store kv.Store, prefix string, providers []configs.OIDCProviderConfig) *OIDCService {
+	registry := make(map[string]*oidcProvider, len(providers))
+	for _, cfg := range providers {
+		registry[cfg.Name] = newOIDCProvider(cfg)
+	}
+	return &OIDCService{
+		repo:      repo,
+		tokens:    tokens,
+		store:     store,
+		prefix:    prefix,
+		providers: registry,
+	}
+}
+
+// Providers 返回已配置的提供方名称
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthURL 生成跳转到提供方的授权地址，并保存本次登录的 state、nonce 和 PKCE verifier
+func (s *OIDCService) AuthURL(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(&oidcState{Provider: providerName, CodeVerifier: verifier, Nonce: nonce})
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.Set(ctx, s.buildKey("state", state), string(data), oidcStateTTL); err != nil {
+		return "", fmt.Errorf("保存登录状态失败: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		log.Printf("OIDC provider %s unavailable: %v", providerName, err)
+		return "", ErrOIDCLoginFailed
+	}
+	return authURL, nil
+}
+
+// Callback 处理提供方回调：校验 state，换取并校验 ID Token，返回关联的本站用户
+// 返回的 bool 表示本次是否新建了账号
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state string) (*models.User, bool, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, false, ErrOIDCProviderNotFound
+	}
+
+	// state 只能使用一次
+	data, err := s.store.GetDel(ctx, s.buildKey("state", state))
+	if err != nil {
+		return nil, false, ErrOIDCStateInvalid
+	}
+	var saved oidcState
+	if err := json.Unmarshal([]byte(data), &saved); err != nil || saved.Provider != providerName {
+		return nil, false, ErrOIDCStateInvalid
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, saved.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC provider %s code exchange failed: %v", providerName, err)
+		return nil, false, ErrOIDCLoginFailed
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, saved.Nonce)
+	if err != nil {
+		log.Printf("OIDC provider %s returned invalid id_token: %v", providerName, err)
+		return nil, false, ErrOIDCLoginFailed
+	}
+
+	return s.resolveUser(ctx, providerName, claims)
+}
+
+// resolveUser 将第三方身份关联到本站用户：
+// 已绑定的身份直接登录；否则按已验证的邮箱关联现有账号，邮箱未注册时创建新账号
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, claims *oidcClaims) (*models.User, bool, error) {
+	identity, err := s.repo.GetIdentity(providerName, claims.Subject)
+	if err == nil {
+		user, err := s.repo.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, false, errors.New("获取用户信息失败")
+		}
+		return user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, errors.New("获取用户信息失败")
+	}
+
+	// 只有提供方确认过的邮箱才能用于关联账号，否则可能被用来接管他人账号
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, false, ErrOIDCEmailUnverified
+	}
+
+	identity = &models.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	now := time.Now()
+
+	user, err := s.repo.GetUserByEmail(claims.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 第三方登录创建的用户使用随机密码占位，之后可通过重置密码设置
+		password, err := randomToken(32)
+		if err != nil {
+			return nil, false, errors.New("创建用户失败")
+		}
+		user = &models.User{
+			Email:           claims.Email,
+			Password:        password,
+			EmailVerifiedAt: &now,
+		}
+		if err := s.repo.CreateUserWithIdentity(user, identity); err != nil {
+			return nil, false, errors.New("创建用户失败")
+		}
+		return user, true, nil
+	}
+	if err != nil {
+		return nil, false, errors.New("获取用户信息失败")
+	}
+
+	if !user.IsEmailVerified() {
+		if err := claimUnverifiedUser(ctx, s.repo, s.tokens, user, now); err != nil {
+			return nil, false, err
+		}
+	}
+	identity.UserID = user.ID
+	if err := s.repo.CreateIdentity(identity); err != nil {
+		return nil, false, errors.New("绑定第三方账号失败")
+	}
+	return user, false, nil
+}
+
+func (s *OIDCService) buildKey(kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, kind, id)
+}
@@ -32,3 +32,61 @@ func (r *Repository) GetUserByID(id uint) (*models.User, error) {
 	err := r.db.First(&user, id).Error
 	return &user, err
 }
+
+// GetUserByGuestDevice 根据设备标识哈希获取游客账号
+func (r *Repository) GetUserByGuestDevice(deviceHash string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("guest_device_hash = ?", deviceHash).First(&user).Error
+	return &user, err
+}
+
+// UpdatePassword 更新用户密码（需传入已加密的密码）
+func (r *Repository) UpdatePassword(id uint, hashedPassword string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
+
+// UpdateFields 更新用户的指定字段
+func (r *Repository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// AdvanceTOTPStep 记录最近一次使用的 TOTP 时间步，只在 step 大于已记录的值时更新。
+// 返回 false 表示该时间步已被并发的请求使用
+func (r *Repository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceRecoveryCodes 在恢复码仍为 old 时替换为 codes。
+// 返回 false 表示恢复码已被并发的请求修改
+func (r *Repository) ReplaceRecoveryCodes(id uint, old, codes string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_recovery_codes = ?", id, old).
+		Update("totp_recovery_codes", codes)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetIdentity 根据提供方和 subject 获取第三方登录身份
+func (r *Repository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+// CreateIdentity 为已有用户绑定第三方登录身份
+func (r *Repository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// CreateUserWithIdentity 在同一事务中创建用户并绑定第三方登录身份
+func (r *Repository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
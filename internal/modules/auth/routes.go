@@ -3,11 +3,48 @@ package auth
 import "github.com/gin-gonic/gin"
 
 // RegisterRoutes 注册认证模块路由
-func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler, authMiddleware, optionalAuthMiddleware gin.HandlerFunc) {
 	// 创建 /auth 子路由组
 	authGroup := router.Group("/auth")
 	{
-		authGroup.POST("/register", handler.Register)
+		authGroup.POST("/register", optionalAuthMiddleware, handler.Register) // 游客登录状态下注册会升级当前游客账号
+		authGroup.POST("/guest", handler.GuestLogin)
 		authGroup.POST("/login", handler.Login)
+		authGroup.POST("/login/code", handler.LoginWithCode)
+		authGroup.POST("/login/2fa", handler.LoginTwoFactor)
+		authGroup.POST("/password/forgot", handler.ForgotPassword)
+		authGroup.POST("/password/reset", handler.ResetPassword)
+		authGroup.POST("/refresh", handler.Refresh)
+		authGroup.POST("/logout", authMiddleware, handler.Logout)
 	}
+
+	// 第三方登录（OIDC）
+	oidc := authGroup.Group("/oidc")
+	{
+		oidc.GET("/providers", handler.OIDCProviders)         // GET /api/v1/auth/oidc/providers - 可用的登录方式
+		oidc.GET("/:provider/login", handler.OIDCLogin)       // GET /api/v1/auth/oidc/:provider/login - 跳转到提供方登录页
+		oidc.GET("/:provider/callback", handler.OIDCCallback) // GET /api/v1/auth/oidc/:provider/callback - 提供方回调
+	}
+
+	// 两步验证管理（需要认证）
+	twoFactor := authGroup.Group("/2fa")
+	twoFactor.Use(authMiddleware)
+	{
+		twoFactor.POST("/setup", handler.SetupTwoFactor)     // POST /api/v1/auth/2fa/setup - 生成密钥和扫码链接
+		twoFactor.POST("/enable", handler.EnableTwoFactor)   // POST /api/v1/auth/2fa/enable - 校验首个验证码并开启
+		twoFactor.POST("/disable", handler.DisableTwoFactor) // POST /api/v1/auth/2fa/disable - 关闭两步验证
+	}
+
+	// 登录设备管理（需要认证）
+	sessions := router.Group("/users/sessions")
+	sessions.Use(authMiddleware)
+	{
+		sessions.GET("", handler.ListSessions)         // GET /api/v1/users/sessions - 列出登录设备
+		sessions.DELETE("/:id", handler.RevokeSession) // DELETE /api/v1/users/sessions/:id - 让设备退出登录
+	}
+}
+
+// RegisterWellKnownRoutes 注册挂载在站点根路径下的公开端点
+func RegisterWellKnownRoutes(router gin.IRouter, handler *Handler) {
+	router.GET("/.well-known/jwks.json", handler.JWKS)
 }
@@ -1,25 +1,57 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"log"
+	"time"
 
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
 
 	"go-tree-hollow/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserExists    = errors.New("用户已存在")
+	ErrGuestUpgraded = errors.New("该设备的游客账号已注册，请使用邮箱登录")
 )
 
 type Service struct {
-	repo *Repository
+	repo      *Repository
+	tokens    *TokenService
+	guard     *LoginGuard
+	twoFactor *TwoFactorService
+	oidc      *OIDCService
+	codes     email.EmailService // 注册、验证码登录和重置密码的验证码，按用途区分
+	sender    *email.Sender
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, tokens *TokenService, guard *LoginGuard, twoFactor *TwoFactorService, oidc *OIDCService, codes email.EmailService, sender *email.Sender) *Service {
+	return &Service{
+		repo:      repo,
+		tokens:    tokens,
+		guard:     guard,
+		twoFactor: twoFactor,
+		oidc:      oidc,
+		codes:     codes,
+		sender:    sender,
+	}
 }
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	Code     string `json:"code" binding:"required"` // 通过 /email/send（purpose=register）获取的邮箱验证码
+	Locale   string `json:"locale"`                  // 邮件通知使用的语言（zh-CN / en），可选
+}
+
+// GuestLoginRequest 游客登录请求
+type GuestLoginRequest struct {
+	DeviceID string `json:"device_id" binding:"required,min=16,max=128"` // 客户端首次启动时生成并保存的随机标识
 }
 
 // LoginRequest 登录请求
@@ -28,18 +60,87 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// Register 注册用户
-func (s *Service) Register(req *RegisterRequest) (*models.User, error) {
+// CodeLoginRequest 验证码登录请求
+type CodeLoginRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required"` // 通过 /email/send（purpose=login）获取的邮箱验证码
+}
+
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Code     string `json:"code" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// TwoFactorLoginRequest 两步验证登录请求
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"` // 密码校验通过后返回的登录挑战令牌
+	Code     string `json:"code" binding:"required"`      // 身份验证器中的6位验证码或恢复码
+}
+
+// TwoFactorCodeRequest 开启或关闭两步验证时提交的验证码
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// LoginResult 登录结果，开启两步验证的用户只返回 MFAToken，需再提交验证码换取令牌
+type LoginResult struct {
+	Tokens   *TokenPair
+	MFAToken string
+}
+
+// SessionResponse 登录设备信息
+type SessionResponse struct {
+	ID         uint   `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	Current    bool   `json:"current"` // 是否为发起请求的设备
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	All bool `json:"all"` // 退出所有设备
+}
+
+// Register 注册用户，邮箱验证码校验通过后才会创建账号
+// guestID 非零时表示由游客账号发起注册：不新建账号，而是为游客账号绑定邮箱和密码，
+// 其帖子、点赞和会话等数据全部保留
+func (s *Service) Register(ctx context.Context, req *RegisterRequest, guestID uint) (*models.User, error) {
 	// 检查用户是否已存在
 	existingUser, err := s.repo.GetUserByEmail(req.Email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("用户已存在")
+		return nil, ErrUserExists
+	}
+
+	// 校验邮箱验证码，证明用户拥有该邮箱
+	if err := s.codes.VerifyCode(ctx, email.PurposeRegister, req.Email, req.Code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if guestID != 0 {
+		return s.upgradeGuest(ctx, guestID, req, now)
 	}
 
 	// 创建新用户
 	user := &models.User{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:           req.Email,
+		Password:        req.Password,
+		EmailVerifiedAt: &now,
+		Locale:          email.NormalizeLocale(req.Locale),
 	}
 
 	if err := s.repo.CreateUser(user); err != nil {
@@ -49,25 +150,341 @@ func (s *Service) Register(req *RegisterRequest) (*models.User, error) {
 	return user, nil
 }
 
-// Login 用户登录
-func (s *Service) Login(req *LoginRequest) (string, error) {
+// upgradeGuest 将游客账号升级为正式账号，并使游客令牌全部失效
+func (s *Service) upgradeGuest(ctx context.Context, guestID uint, req *RegisterRequest, now time.Time) (*models.User, error) {
+	user, err := s.repo.GetUserByID(guestID)
+	if err != nil || !user.IsGuest() {
+		return nil, ErrGuestUpgraded
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, errors.New("密码加密失败")
+	}
+	if err := s.repo.UpdateFields(user.ID, map[string]interface{}{
+		"email":             req.Email,
+		"password":          hashedPassword,
+		"role":              models.RoleUser,
+		"email_verified_at": now,
+		"guest_device_hash": nil,
+		"locale":            email.NormalizeLocale(req.Locale),
+	}); err != nil {
+		return nil, errors.New("注册失败")
+	}
+
+	// 游客令牌携带的角色和设备绑定已过时，需使用邮箱重新登录
+	if err := s.tokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to revoke guest tokens for user %d: %v", user.ID, err)
+	}
+
+	user.Email = req.Email
+	user.Role = models.RoleUser
+	user.EmailVerifiedAt = &now
+	user.GuestDeviceHash = nil
+	return user, nil
+}
+
+// LoginAsGuest 游客登录：同一设备始终对应同一个游客账号，首次登录时自动创建
+// 返回的 bool 表示本次是否新建了账号
+func (s *Service) LoginAsGuest(req *GuestLoginRequest, client ClientInfo) (*TokenPair, bool, error) {
+	deviceHash := utils.DeviceFingerprint(req.DeviceID)
+
+	created := false
+	user, err := s.repo.GetUserByGuestDevice(deviceHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 游客没有邮箱和密码，使用不可送达的占位邮箱和随机密码
+		password, err := randomToken(32)
+		if err != nil {
+			return nil, false, errors.New("创建游客账号失败")
+		}
+		user = &models.User{
+			Email:           "guest-" + deviceHash[:24] + "@" + models.GuestEmailDomain,
+			Password:        password,
+			Role:            models.RoleGuest,
+			GuestDeviceHash: &deviceHash,
+		}
+		if err := s.repo.CreateUser(user); err != nil {
+			return nil, false, errors.New("创建游客账号失败")
+		}
+		created = true
+	} else if err != nil {
+		return nil, false, errors.New("获取用户信息失败")
+	}
+
+	tokens, err := s.tokens.Issue(user, client)
+	if err != nil {
+		return nil, false, errors.New("生成令牌失败")
+	}
+	return tokens, created, nil
+}
+
+// Login 用户登录，client.IP 同时用于按IP限制暴力破解
+func (s *Service) Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*LoginResult, error) {
+	if err := s.guard.Check(ctx, req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByEmail(req.Email)
 	if err != nil {
-		return "", errors.New("邮箱或密码错误")
+		// 未注册的邮箱同样计入失败次数，避免通过响应差异探测账号
+		s.guard.RecordFailure(ctx, req.Email, client.IP)
+		return nil, ErrInvalidCredentials
 	}
 
 	// 验证密码
 	if !utils.CheckPassword(req.Password, user.Password) {
-		return "", errors.New("邮箱或密码错误")
+		locked, err := s.guard.RecordFailure(ctx, req.Email, client.IP)
+		if err != nil {
+			log.Printf("Failed to record login failure for %s: %v", req.Email, err)
+		}
+		if locked {
+			go s.sendLockAlert(user, client.IP)
+			// 返回带有实际锁定时长的错误
+			if err := s.guard.Check(ctx, req.Email, client.IP); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidCredentials
+	}
+	// 开启两步验证的账号在第二步通过后才清除失败计数，
+	// 否则知道密码的攻击者每次登录都会清零计数，可以无限次尝试验证码
+	if !user.IsTwoFactorEnabled() {
+		s.guard.Reset(ctx, req.Email)
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// LoginWithCode 使用邮箱验证码登录，首次验证通过的邮箱会自动注册
+// 返回的 bool 表示本次是否新建了账号
+func (s *Service) LoginWithCode(ctx context.Context, req *CodeLoginRequest, client ClientInfo) (*LoginResult, bool, error) {
+	if err := s.codes.VerifyCode(ctx, email.PurposeLogin, req.Email, req.Code); err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	created := false
+	user, err := s.repo.GetUserByEmail(req.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 无密码用户使用随机密码占位，之后可通过重置密码设置
+		password, err := randomToken(32)
+		if err != nil {
+			return nil, false, errors.New("创建用户失败")
+		}
+		user = &models.User{
+			Email:           req.Email,
+			Password:        password,
+			EmailVerifiedAt: &now,
+		}
+		if err := s.repo.CreateUser(user); err != nil {
+			return nil, false, errors.New("创建用户失败")
+		}
+		created = true
+	} else if err != nil {
+		return nil, false, errors.New("获取用户信息失败")
+	} else if !user.IsEmailVerified() {
+		// 验证码登录同样证明了邮箱归属
+		if err := claimUnverifiedUser(ctx, s.repo, s.tokens, user, now); err != nil {
+			return nil, false, err
+		}
+	}
+
+	result, err := s.completeLogin(ctx, user, client)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, created, nil
+}
+
+// claimUnverifiedUser 将邮箱未验证的账号交给邮箱的真正所有者。
+// 该账号可能是他人抢先用这个邮箱注册的，注册时设置的密码和两步验证都不可信：
+// 替换为随机密码、关闭两步验证，并吊销已签发的全部令牌，之后只能通过第三方登录、验证码登录或重置密码进入
+func claimUnverifiedUser(ctx context.Context, repo *Repository, tokens *TokenService, user *models.User, now time.Time) error {
+	password, err := randomToken(32)
+	if err != nil {
+		return errors.New("更新用户信息失败")
 	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return errors.New("更新用户信息失败")
+	}
+
+	if err := repo.UpdateFields(user.ID, map[string]interface{}{
+		"password":            hashedPassword,
+		"email_verified_at":   now,
+		"totp_secret":         "",
+		"totp_enabled_at":     nil,
+		"totp_last_step":      0,
+		"totp_recovery_codes": "",
+	}); err != nil {
+		return errors.New("更新用户信息失败")
+	}
+	if err := tokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		return errors.New("吊销用户令牌失败")
+	}
+
+	user.Password = hashedPassword
+	user.EmailVerifiedAt = &now
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	user.TOTPRecoveryCodes = ""
+	return nil
+}
+
+// LoginTwoFactor 提交两步验证码完成登录
+func (s *Service) LoginTwoFactor(ctx context.Context, req *TwoFactorLoginRequest, client ClientInfo) (*TokenPair, error) {
+	user, err := s.twoFactor.CompleteChallenge(ctx, req.MFAToken, req.Code, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokens.Issue(user, client)
+	if err != nil {
+		return nil, errors.New("生成令牌失败")
+	}
+	return tokens, nil
+}
+
+// OIDCProviders 返回可用的第三方登录方式
+func (s *Service) OIDCProviders() []string {
+	return s.oidc.Providers()
+}
+
+// OIDCAuthURL 生成跳转到第三方登录页的地址
+func (s *Service) OIDCAuthURL(ctx context.Context, provider string) (string, error) {
+	return s.oidc.AuthURL(ctx, provider)
+}
+
+// LoginWithOIDC 完成第三方登录回调，返回的 bool 表示本次是否新建了账号
+func (s *Service) LoginWithOIDC(ctx context.Context, provider, code, state string, client ClientInfo) (*LoginResult, bool, error) {
+	user, created, err := s.oidc.Callback(ctx, provider, code, state)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := s.completeLogin(ctx, user, client)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, created, nil
+}
+
+// completeLogin 第一步校验通过后签发令牌，开启两步验证的用户改为创建登录挑战
+func (s *Service) completeLogin(ctx context.Context, user *models.User, client ClientInfo) (*LoginResult, error) {
+	if user.IsTwoFactorEnabled() {
+		mfaToken, err := s.twoFactor.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, errors.New("创建登录挑战失败")
+		}
+		return &LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	// 创建会话并签发访问令牌和刷新令牌
+	tokens, err := s.tokens.Issue(user, client)
+	if err != nil {
+		return nil, errors.New("生成令牌失败")
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// SetupTwoFactor 生成两步验证密钥
+func (s *Service) SetupTwoFactor(userID uint) (*TwoFactorSetupResponse, error) {
+	return s.twoFactor.Setup(userID)
+}
 
-	// 生成JWT
-	token, err := utils.GenerateToken(user.ID, user.Email)
+// EnableTwoFactor 校验首个验证码后开启两步验证，返回恢复码
+func (s *Service) EnableTwoFactor(userID uint, req *TwoFactorCodeRequest) ([]string, error) {
+	return s.twoFactor.Enable(userID, req.Code)
+}
+
+// DisableTwoFactor 关闭两步验证
+func (s *Service) DisableTwoFactor(ctx context.Context, userID uint, req *TwoFactorCodeRequest, client ClientInfo) error {
+	return s.twoFactor.Disable(ctx, userID, req.Code, client.IP)
+}
+
+// ForgotPassword 向已注册邮箱发送重置密码验证码
+// 邮箱未注册时同样返回成功，避免泄露账号是否存在
+func (s *Service) ForgotPassword(ctx context.Context, req *ForgotPasswordRequest) error {
+	user, err := s.repo.GetUserByEmail(req.Email)
 	if err != nil {
-		return "", errors.New("生成令牌失败")
+		return nil
 	}
+	return s.codes.SendVerificationCode(ctx, email.PurposeResetPassword, req.Email, user.Locale)
+}
 
-	return token, nil
+// ResetPassword 校验重置验证码后设置新密码，并使该用户已签发的令牌全部失效
+func (s *Service) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	user, err := s.repo.GetUserByEmail(req.Email)
+	if err != nil {
+		return email.ErrCodeInvalid
+	}
+
+	if err := s.codes.VerifyCode(ctx, email.PurposeResetPassword, req.Email, req.Code); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.repo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return errors.New("重置密码失败")
+	}
+
+	return s.tokens.RevokeAllForUser(ctx, user.ID)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+func (s *Service) Refresh(ctx context.Context, req *RefreshRequest, client ClientInfo) (*TokenPair, error) {
+	return s.tokens.Refresh(ctx, req.RefreshToken, client)
+}
+
+// Logout 退出登录，All 为 true 时吊销该用户在所有设备上的令牌
+func (s *Service) Logout(ctx context.Context, claims *utils.Claims, req *LogoutRequest) error {
+	if req.All {
+		return s.tokens.RevokeAllForUser(ctx, claims.UserID)
+	}
+	return s.tokens.Logout(ctx, claims)
+}
+
+// ListSessions 列出用户当前登录的设备，currentSessionID 对应的会话会被标记为当前设备
+func (s *Service) ListSessions(userID, currentSessionID uint) ([]*SessionResponse, error) {
+	sessions, err := s.tokens.ListSessions(userID)
+	if err != nil {
+		return nil, errors.New("获取登录设备失败")
+	}
+
+	responses := make([]*SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, &SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02 15:04:05"),
+			LastSeenAt: session.LastSeenAt.Format("2006-01-02 15:04:05"),
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return responses, nil
+}
+
+// RevokeSession 让用户的某台设备退出登录
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	return s.tokens.RevokeSession(ctx, userID, sessionID)
+}
+
+// sendLockAlert 通知用户其账号因多次登录失败被锁定
+func (s *Service) sendLockAlert(user *models.User, clientIP string) {
+	data := &email.SecurityAlertData{
+		Event: email.AlertAccountLocked,
+		Time:  time.Now().Format("2006-01-02 15:04:05"),
+		IP:    clientIP,
+	}
+	if err := s.sender.SendTemplate(user.Email, user.Locale, email.TemplateSecurityAlert, data); err != nil {
+		log.Printf("Failed to send lock alert to %s: %v", user.Email, err)
+	}
 }
 
 // GetProfile 获取用户信息（示例业务）
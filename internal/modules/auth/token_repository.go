@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/kv"
+
+	"gorm.io/gorm"
+)
+
+// TokenRepository 负责令牌状态的持久化：
+// 刷新令牌保存在数据库中，访问令牌的吊销记录保存在键值存储中并随令牌过期自动清除。
+type TokenRepository struct {
+	db     *gorm.DB
+	store  kv.Store
+	prefix string
+}
+
+func NewTokenRepository(db *gorm.DB, store kv.Store, prefix string) *TokenRepository {
+	return &TokenRepository{
+		db:     db,
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+// CreateRefreshToken 保存刷新令牌
+func (r *TokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetRefreshTokenByHash 根据令牌哈希获取刷新令牌
+func (r *TokenRepository) GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// MarkRefreshTokenUsed 将刷新令牌标记为已使用
+// 只有仍处于可用状态的令牌才会被更新，返回 false 表示令牌已被并发请求抢先使用
+func (r *TokenRepository) MarkRefreshTokenUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateSession 创建登录会话
+func (r *TokenRepository) CreateSession(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetSession 根据ID获取会话
+func (r *TokenRepository) GetSession(id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.First(&session, id).Error
+	return &session, err
+}
+
+// ListActiveSessions 获取用户所有未吊销的会话，最近活跃的在前
+func (r *TokenRepository) ListActiveSessions(userID uint) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// TouchSession 更新会话的最近活跃时间和IP
+func (r *TokenRepository) TouchSession(id uint, ip string, at time.Time) error {
+	updates := map[string]interface{}{"last_seen_at": at}
+	if ip != "" {
+		updates["ip"] = ip
+	}
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// RevokeSession 吊销会话及其所有刷新令牌
+func (r *TokenRepository) RevokeSession(id uint) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("session_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeAllForUser 吊销用户所有会话及刷新令牌
+func (r *TokenRepository) RevokeAllForUser(userID uint) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeAccessToken 将访问令牌的 jti 加入黑名单，保留到令牌自然过期为止
+func (r *TokenRepository) RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.store.Set(ctx, r.buildKey("revoked", jti), "1", ttl)
+}
+
+// IsAccessTokenRevoked 检查访问令牌的 jti 是否在黑名单中
+func (r *TokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := r.store.Exists(ctx, r.buildKey("revoked", jti))
+	if err != nil {
+		return false, fmt.Errorf("查询令牌状态失败: %w", err)
+	}
+	return revoked, nil
+}
+
+// MarkSessionRevoked 标记会话已吊销，使其访问令牌立即失效
+func (r *TokenRepository) MarkSessionRevoked(ctx context.Context, sessionID uint, ttl time.Duration) error {
+	key := r.buildKey("session_revoked", strconv.FormatUint(uint64(sessionID), 10))
+	return r.store.Set(ctx, key, "1", ttl)
+}
+
+// IsSessionRevoked 检查会话是否已被标记为吊销
+func (r *TokenRepository) IsSessionRevoked(ctx context.Context, sessionID uint) (bool, error) {
+	key := r.buildKey("session_revoked", strconv.FormatUint(uint64(sessionID), 10))
+	revoked, err := r.store.Exists(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("查询会话状态失败: %w", err)
+	}
+	return revoked, nil
+}
+
+// ShouldTouchSession 限制会话活跃时间的写入频率，interval 内只返回一次 true
+func (r *TokenRepository) ShouldTouchSession(ctx context.Context, sessionID uint, interval time.Duration) bool {
+	key := r.buildKey("session_seen", strconv.FormatUint(uint64(sessionID), 10))
+	ok, err := r.store.SetNX(ctx, key, "1", interval)
+	return err == nil && ok
+}
+
+// SetRevokedBefore 记录用户的令牌失效时间点（毫秒精度），不晚于该时间签发的访问令牌均视为已吊销
+func (r *TokenRepository) SetRevokedBefore(ctx context.Context, userID uint, at time.Time, ttl time.Duration) error {
+	key := r.buildKey("revoked_before", strconv.FormatUint(uint64(userID), 10))
+	return r.store.Set(ctx, key, strconv.FormatInt(at.UnixMilli(), 10), ttl)
+}
+
+// GetRevokedBefore 获取用户的令牌失效时间点，不存在时返回零值
+func (r *TokenRepository) GetRevokedBefore(ctx context.Context, userID uint) (time.Time, error) {
+	key := r.buildKey("revoked_before", strconv.FormatUint(uint64(userID), 10))
+	value, err := r.store.Get(ctx, key)
+	if errors.Is(err, kv.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("查询令牌状态失败: %w", err)
+	}
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("查询令牌状态失败: %w", err)
+	}
+	return time.UnixMilli(ts), nil
+}
+
+func (r *TokenRepository) buildKey(kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", r.prefix, kind, id)
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，请重新登录")
+	ErrSessionNotFound     = errors.New("会话不存在")
+)
+
+// sessionTouchInterval 会话最近活跃时间的最小更新间隔
+const sessionTouchInterval = time.Minute
+
+// ClientInfo 发起登录的客户端信息，记录在会话中
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair 登录或刷新后返回给客户端的令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+}
+
+// TokenService 负责签发、轮换和吊销令牌
+type TokenService struct {
+	repo       *TokenRepository
+	users      *Repository
+	refreshTTL time.Duration
+}
+
+func NewTokenService(repo *TokenRepository, users *Repository, cfg *configs.Config) *TokenService {
+	return &TokenService{
+		repo:       repo,
+		users:      users,
+		refreshTTL: 24 * time.Hour * time.Duration(cfg.JWTExpireDays),
+	}
+}
+
+// Issue 为一次新的登录创建会话并签发令牌
+func (s *TokenService) Issue(user *models.User, client ClientInfo) (*TokenPair, error) {
+	session := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(client.UserAgent, 512),
+		IP:         client.IP,
+		LastSeenAt: time.Now(),
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.issue(user, session.ID)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+// 已失效的令牌被再次使用时，说明令牌可能已泄露，整个会话会被吊销
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	stored, err := s.repo.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	if stored.UsedAt != nil {
+		s.revokeSession(ctx, stored.SessionID)
+		return nil, ErrRefreshTokenReused
+	}
+	if !stored.IsActive() {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 并发请求同时使用同一个令牌时只有一个能成功
+	ok, err := s.repo.MarkRefreshTokenUsed(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.revokeSession(ctx, stored.SessionID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	s.repo.TouchSession(stored.SessionID, client.IP, time.Now())
+	return s.issue(user, stored.SessionID)
+}
+
+// Logout 吊销当前访问令牌及其所属会话
+func (s *TokenService) Logout(ctx context.Context, claims *utils.Claims) error {
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if claims.SessionID == 0 {
+		return nil
+	}
+	return s.revokeSession(ctx, claims.SessionID)
+}
+
+// ListSessions 列出用户当前有效的登录会话
+func (s *TokenService) ListSessions(userID uint) ([]*models.Session, error) {
+	return s.repo.ListActiveSessions(userID)
+}
+
+// RevokeSession 吊销用户的某个会话，该设备需要重新登录
+func (s *TokenService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.repo.GetSession(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, sessionID)
+}
+
+// RevokeAllForUser 使用户在所有设备上签发的令牌全部失效
+func (s *TokenService) RevokeAllForUser(ctx context.Context, userID uint) error {
+	if err := s.repo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	// 访问令牌的最长寿命即为标记需要保留的时间
+	return s.repo.SetRevokedBefore(ctx, userID, time.Now(), utils.AccessTokenTTL())
+}
+
+// IsRevoked 检查签名有效的访问令牌是否已被吊销或所属会话已失效，供认证中间件调用
+func (s *TokenService) IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := s.repo.IsAccessTokenRevoked(ctx, claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.SessionID != 0 {
+		revoked, err := s.repo.IsSessionRevoked(ctx, claims.SessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedBefore, err := s.repo.GetRevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	if revokedBefore.IsZero() || claims.IssuedAt == nil {
+		return false, nil
+	}
+	// 与吊销时间点在同一毫秒内签发的令牌也视为已吊销
+	return !claims.IssuedAt.Time.After(revokedBefore), nil
+}
+
+// Touch 记录会话的最近活跃时间，写入频率受 sessionTouchInterval 限制
+func (s *TokenService) Touch(ctx context.Context, claims *utils.Claims, ip string) {
+	if claims.SessionID == 0 || !s.repo.ShouldTouchSession(ctx, claims.SessionID, sessionTouchInterval) {
+		return
+	}
+	s.repo.TouchSession(claims.SessionID, ip, time.Now())
+}
+
+func (s *TokenService) issue(user *models.User, sessionID uint) (*TokenPair, error) {
+	// 游客令牌绑定到其设备，刷新时同样如此
+	var deviceID string
+	if user.IsGuest() && user.GuestDeviceHash != nil {
+		deviceID = *user.GuestDeviceHash
+	}
+
+	accessToken, err := utils.GenerateToken(user.ID, user.Email, user.Role, sessionID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// revokeSession 吊销会话，并标记使其访问令牌立即失效
+func (s *TokenService) revokeSession(ctx context.Context, sessionID uint) error {
+	if err := s.repo.RevokeSession(sessionID); err != nil {
+		return err
+	}
+	return s.repo.MarkSessionRevoked(ctx, sessionID, utils.AccessTokenTTL())
+}
+
+func (s *TokenService) revokeAccessToken(ctx context.Context, claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return s.repo.RevokeAccessToken(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+}
+
+// randomToken 生成 size 字节的随机数并编码为 URL 安全的字符串
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-tree-hollow/pkg/kv"
+	"go-tree-hollow/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestIsRevokedBoundary(t *testing.T) {
+	revokedAt := time.Date(2026, 10, 16, 8, 0, 0, 500*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt *time.Time
+		want     bool
+	}{
+		{"早于吊销时间", ptr(revokedAt.Add(-time.Second)), true},
+		{"早一毫秒", ptr(revokedAt.Add(-time.Millisecond)), true},
+		{"与吊销时间相同", ptr(revokedAt), true},
+		{"晚一毫秒", ptr(revokedAt.Add(time.Millisecond)), false},
+		{"同一秒内稍后签发", ptr(revokedAt.Add(300 * time.Millisecond)), false},
+		{"没有签发时间", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewTokenRepository(nil, kv.NewMemoryStore(), "test")
+			service := &TokenService{repo: repo}
+			if err := repo.SetRevokedBefore(ctx, 1, revokedAt, time.Hour); err != nil {
+				t.Fatalf("SetRevokedBefore: %v", err)
+			}
+
+			claims := &utils.Claims{UserID: 1}
+			if tt.issuedAt != nil {
+				claims.IssuedAt = jwt.NewNumericDate(*tt.issuedAt)
+			}
+			revoked, err := service.IsRevoked(ctx, claims)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRevokedWithoutRecord(t *testing.T) {
+	service := &TokenService{repo: NewTokenRepository(nil, kv.NewMemoryStore(), "test")}
+	claims := &utils.Claims{UserID: 1}
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+
+	if revoked, err := service.IsRevoked(context.Background(), claims); err != nil || revoked {
+		t.Errorf("IsRevoked = (%v, %v), want (false, nil)", revoked, err)
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
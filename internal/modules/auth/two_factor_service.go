@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/kv"
+	"go-tree-hollow/pkg/utils"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("两步验证已开启")
+	ErrTwoFactorNotEnabled     = errors.New("两步验证未开启")
+	ErrTwoFactorNotSetup       = errors.New("请先获取两步验证密钥")
+	ErrTwoFactorCodeInvalid    = errors.New("两步验证码错误")
+	ErrMFATokenInvalid         = errors.New("登录已过期，请重新输入密码")
+)
+
+const (
+	recoveryCodeCount    = 10
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaChallengeAttempts = 5 // 每次登录挑战最多可尝试的验证码次数
+)
+
+// TwoFactorSetupResponse 开启两步验证时返回的密钥信息
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// 链接，前端可生成二维码
+}
+
+// TwoFactorService 管理 TOTP 两步验证的开启、关闭和登录时的第二步校验
+type TwoFactorService struct {
+	repo   *Repository
+	guard  *LoginGuard // 验证码错误与密码错误共用失败计数
+	store  kv.Store
+	prefix string
+	issuer string
+}
+
+func NewTwoFactorService(repo *Repository, guard *LoginGuard, store kv.Store, prefix, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		repo:   repo,
+		guard:  guard,
+		store:  store,
+		prefix: prefix,
+		issuer: issuer,
+	}
+}
+
+// Setup 生成新的 TOTP 密钥，需要调用 Enable 校验首个验证码后才会生效
+func (s *TwoFactorService) Setup(userID uint) (*TwoFactorSetupResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("生成密钥失败")
+	}
+	if err := s.repo.UpdateFields(userID, map[string]interface{}{"totp_secret": secret}); err != nil {
+		return nil, errors.New("保存密钥失败")
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Enable 校验首个验证码后开启两步验证，返回一次性恢复码（仅展示这一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashed, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, errors.New("生成恢复码失败")
+	}
+	if err := s.repo.UpdateFields(userID, map[string]interface{}{
+		"totp_enabled_at":     time.Now(),
+		"totp_last_step":      step,
+		"totp_recovery_codes": hashed,
+	}); err != nil {
+		return nil, errors.New("开启两步验证失败")
+	}
+
+	return codes, nil
+}
+
+// Disable 校验验证码或恢复码后关闭两步验证，ip 用于限制尝试次数
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code, ip string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if !user.IsTwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.verifyGuarded(ctx, user, code, ip); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateFields(userID, map[string]interface{}{
+		"totp_secret":         "",
+		"totp_enabled_at":     nil,
+		"totp_last_step":      0,
+		"totp_recovery_codes": "",
+	})
+}
+
+// CreateChallenge 密码校验通过后创建登录挑战，客户端凭返回的令牌提交第二步验证码
+func (s *TwoFactorService) CreateChallenge(ctx context.Context, userID uint) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	key := s.buildKey("challenge", hashToken(token))
+	if err := s.store.Set(ctx, key, strconv.FormatUint(uint64(userID), 10), mfaChallengeTTL); err != nil {
+		return "", fmt.Errorf("创建登录挑战失败: %w", err)
+	}
+	return token, nil
+}
+
+// CompleteChallenge 校验登录挑战的验证码或恢复码，成功后返回对应用户。
+// 被限制时返回 *LoginBlockedError
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, mfaToken, code, ip string) (*models.User, error) {
+	key := s.buildKey("challenge", hashToken(mfaToken))
+	value, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, ErrMFATokenInvalid
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, ErrMFATokenInvalid
+	}
+
+	// 限制同一挑战的尝试次数，超过后需要重新输入密码
+	attemptsKey := s.buildKey("attempts", hashToken(mfaToken))
+	attempts, err := s.store.Incr(ctx, attemptsKey, mfaChallengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("校验登录挑战失败: %w", err)
+	}
+	if attempts > mfaChallengeAttempts {
+		s.store.Del(ctx, key, attemptsKey)
+		return nil, ErrMFATokenInvalid
+	}
+
+	user, err := s.repo.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, ErrMFATokenInvalid
+	}
+	if err := s.verifyGuarded(ctx, user, code, ip); err != nil {
+		return nil, err
+	}
+
+	s.store.Del(ctx, key, attemptsKey)
+	return user, nil
+}
+
+// verifyGuarded 在登录限制下调用 verify：错误的验证码计入用户邮箱和IP的失败次数，
+// 通过后才清除。每次登录挑战只能尝试几次，但重新输入密码即可获得新的挑战，只靠挑战的次数限制无法阻止暴力破解
+func (s *TwoFactorService) verifyGuarded(ctx context.Context, user *models.User, code, ip string) error {
+	if err := s.guard.Check(ctx, user.Email, ip); err != nil {
+		return err
+	}
+	if err := s.verify(user, code); err != nil {
+		if errors.Is(err, ErrTwoFactorCodeInvalid) {
+			if locked, _ := s.guard.RecordFailure(ctx, user.Email, ip); locked {
+				// 返回带有实际锁定时长的错误
+				if blocked := s.guard.Check(ctx, user.Email, ip); blocked != nil {
+					return blocked
+				}
+			}
+		}
+		return err
+	}
+	s.guard.Reset(ctx, user.Email)
+	return nil
+}
+
+// verify 校验 TOTP 验证码或恢复码，恢复码使用后即作废
+func (s *TwoFactorService) verify(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// 同一时间窗口内的验证码只能使用一次，条件更新保证并发提交同一验证码时只有一个请求成功
+		if step <= user.TOTPLastStep {
+			return ErrTwoFactorCodeInvalid
+		}
+		updated, err := s.repo.AdvanceTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	var hashes []string
+	if user.TOTPRecoveryCodes != "" {
+		if err := json.Unmarshal([]byte(user.TOTPRecoveryCodes), &hashes); err != nil {
+			return ErrTwoFactorCodeInvalid
+		}
+	}
+	normalized := strings.ToLower(code)
+	for i, hash := range hashes {
+		if utils.CheckPassword(normalized, hash) {
+			// 只在恢复码未被其他请求改动时作废，同一恢复码不能被并发使用两次
+			remaining, _ := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+			updated, err := s.repo.ReplaceRecoveryCodes(user.ID, user.TOTPRecoveryCodes, string(remaining))
+			if err != nil {
+				return err
+			}
+			if !updated {
+				return ErrTwoFactorCodeInvalid
+			}
+			return nil
+		}
+	}
+	return ErrTwoFactorCodeInvalid
+}
+
+func (s *TwoFactorService) buildKey(kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, kind, id)
+}
+
+// generateRecoveryCodes 生成恢复码，返回明文（展示给用户）和 bcrypt 哈希列表（JSON）
+func generateRecoveryCodes() ([]string, string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))
+		code := raw[:4] + "-" + raw[4:]
+		hash, err := utils.HashPassword(code)
+		if err != nil {
+			return nil, "", err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(data), nil
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"go-tree-hollow/pkg/pagination"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -49,6 +51,7 @@ func (h *Handler) GetConversations(c *gin.Context) {
 // @Param userId path int true "Other user's ID"
 // @Param page query int false "Page number" default(1)
 // @Param pageSize query int false "Page size" default(50)
+// @Param cursor query string false "Keyset cursor; empty for the newest page, then the previous next_cursor"
 // @Success 200 {array} models.Message
 // @Router /chat/messages/{userId} [get]
 func (h *Handler) GetMessages(c *gin.Context) {
@@ -69,6 +72,26 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		pageSize = 50
 	}
 
+	// Cursor mode: pass an empty cursor for the newest page, then next_cursor to load older messages
+	if raw, ok := c.GetQuery("cursor"); ok {
+		cursor, err := pagination.Decode(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		messages, next, err := h.service.GetMessagesByCursor(currentUserID, uint(otherUserID), cursor, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+			return
+		}
+
+		_ = h.service.MarkConversationAsRead(currentUserID, uint(otherUserID))
+
+		c.JSON(http.StatusOK, gin.H{"data": messages, "next_cursor": next})
+		return
+	}
+
 	messages, err := h.service.GetMessages(currentUserID, uint(otherUserID), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +14,7 @@ type Repository interface {
 	CreateMessage(message *models.Message) error
 	GetMessageByID(id uint) (*models.Message, error)
 	GetMessagesBetweenUsers(user1ID, user2ID uint, limit, offset int) ([]*models.Message, error)
+	GetMessagesBetweenUsersByCursor(user1ID, user2ID uint, cursor *pagination.Cursor, limit int) ([]*models.Message, error)
 	MarkMessageAsRead(messageID uint) error
 	MarkAllMessagesAsRead(senderID, receiverID uint) error
 	GetUnreadCount(userID uint) (int64, error)
@@ -59,6 +61,21 @@ func (r *repository) GetMessagesBetweenUsers(user1ID, user2ID uint, limit, offse
 	return messages, err
 }
 
+// GetMessagesBetweenUsersByCursor retrieves messages between two users older than the cursor,
+// newest first, without an OFFSET scan
+func (r *repository) GetMessagesBetweenUsersByCursor(user1ID, user2ID uint, cursor *pagination.Cursor, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := r.db.
+		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+			user1ID, user2ID, user2ID, user1ID).
+		Scopes(pagination.Scope(cursor)).
+		Preload("Sender").
+		Preload("Receiver").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
 // MarkMessageAsRead marks a single message as read
 func (r *repository) MarkMessageAsRead(messageID uint) error {
 	now := time.Now()
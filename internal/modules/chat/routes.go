@@ -1,15 +1,14 @@
 package chat
 
 import (
-	"go-tree-hollow/internal/middleware"
-
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterRoutes registers chat routes on the given router group
-func RegisterRoutes(rg *gin.RouterGroup, handler *Handler) {
+// RegisterRoutes registers chat routes on the given router group.
+// permissionMiddleware runs after authentication and rejects users who may not chat (e.g. guests).
+func RegisterRoutes(rg *gin.RouterGroup, handler *Handler, authMiddleware, permissionMiddleware gin.HandlerFunc) {
 	chatGroup := rg.Group("/chat")
-	chatGroup.Use(middleware.AuthRequired())
+	chatGroup.Use(authMiddleware, permissionMiddleware)
 	{
 		chatGroup.GET("/conversations", handler.GetConversations)
 		chatGroup.GET("/messages/:userId", handler.GetMessages)
@@ -19,5 +18,5 @@ func RegisterRoutes(rg *gin.RouterGroup, handler *Handler) {
 	}
 
 	// WebSocket endpoint (also requires auth)
-	rg.GET("/ws/chat", middleware.AuthRequired(), handler.HandleWebSocket)
+	rg.GET("/ws/chat", authMiddleware, permissionMiddleware, handler.HandleWebSocket)
 }
@@ -2,6 +2,8 @@ package chat
 
 import (
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
+	"time"
 )
 
 // Service defines the interface for chat business logic
@@ -10,6 +12,9 @@ type Service interface {
 	SendMessage(senderID, receiverID uint, content string) (*models.Message, error)
 	// GetMessages retrieves messages between two users
 	GetMessages(currentUserID, otherUserID uint, page, pageSize int) ([]*models.Message, error)
+	// GetMessagesByCursor retrieves messages older than the cursor, newest first, and the cursor
+	// of the next page (empty when there are no older messages)
+	GetMessagesByCursor(currentUserID, otherUserID uint, cursor *pagination.Cursor, limit int) ([]*models.Message, string, error)
 	// GetConversations retrieves all conversations for a user
 	GetConversations(userID uint) ([]*ConversationResponse, error)
 	// MarkAsRead marks a message as read
@@ -77,6 +82,19 @@ func (s *service) GetMessages(currentUserID, otherUserID uint, page, pageSize in
 	return s.repo.GetMessagesBetweenUsers(currentUserID, otherUserID, pageSize, offset)
 }
 
+// GetMessagesByCursor retrieves messages between two users page by page using a keyset cursor
+func (s *service) GetMessagesByCursor(currentUserID, otherUserID uint, cursor *pagination.Cursor, limit int) ([]*models.Message, string, error) {
+	// Fetch one extra row to tell whether there is another page
+	messages, err := s.repo.GetMessagesBetweenUsersByCursor(currentUserID, otherUserID, cursor, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	messages, next := pagination.Next(messages, limit, func(m *models.Message) (time.Time, uint) {
+		return m.CreatedAt, m.ID
+	})
+	return messages, next, nil
+}
+
 // GetConversations retrieves all conversations for a user with metadata
 func (s *service) GetConversations(userID uint) ([]*ConversationResponse, error) {
 	conversations, err := s.repo.GetConversationsByUserID(userID)
@@ -23,7 +23,12 @@ func (h *EmailHandler) SendVerificationCode(c *gin.Context) {
         return
     }
     
-    if err := h.emailService.SendVerificationCode(c.Request.Context(), req.Email); err != nil {
+    locale := req.Locale
+    if locale == "" {
+        locale = c.GetHeader("Accept-Language")
+    }
+
+    if err := h.emailService.SendVerificationCode(c.Request.Context(), req.Purpose, req.Email, locale); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
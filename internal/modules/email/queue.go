@@ -0,0 +1,255 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+)
+
+var ErrQueuedEmailNotFound = errors.New("邮件不存在或不在失败列表中")
+
+// queueBatchSize 每轮最多领取的邮件数量
+const queueBatchSize = 20
+
+// maxLastErrorLength 记录的失败原因的最大长度
+const maxLastErrorLength = 1000
+
+// QueueStats 发送队列的状态
+type QueueStats struct {
+	Pending         int64                 `json:"pending"`           // 等待发送或等待重试
+	Sending         int64                 `json:"sending"`           // 正在发送
+	Dead            int64                 `json:"dead"`              // 多次失败后不再重试
+	OldestPendingAt *time.Time            `json:"oldest_pending_at"` // 最早入队且尚未发送的邮件的入队时间
+	DeadLetters     []*models.QueuedEmail `json:"dead_letters"`      // 最近失败的邮件，不含正文
+}
+
+// Queue 持久化的邮件发送队列，本身也是一种 Transport：
+// Send 只将邮件写入数据库，由后台任务通过实际的 Transport 发送，失败后按指数退避重试，
+// 超过最大次数后移入失败列表，由管理员排查后手动重发。
+type Queue struct {
+	repo      *QueueRepository
+	transport Transport
+	cfg       configs.MailQueueConfig
+	wake      chan struct{}
+}
+
+func NewQueue(repo *QueueRepository, transport Transport, cfg configs.MailQueueConfig) *Queue {
+	return &Queue{
+		repo:      repo,
+		transport: transport,
+		cfg:       cfg,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Send 将邮件加入队列，立即返回
+func (q *Queue) Send(msg *Message) error {
+	var headers []byte
+	if len(msg.Headers) > 0 {
+		var err error
+		if headers, err = json.Marshal(msg.Headers); err != nil {
+			return fmt.Errorf("邮件加入队列失败: %w", err)
+		}
+	}
+	email := &models.QueuedEmail{
+		From:          msg.From,
+		To:            msg.To,
+		Subject:       msg.Subject,
+		HTMLBody:      msg.HTMLBody,
+		TextBody:      msg.TextBody,
+		Headers:       string(headers),
+		Status:        models.QueuedEmailPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := q.repo.Enqueue(email); err != nil {
+		return fmt.Errorf("邮件加入队列失败: %w", err)
+	}
+	q.notify()
+	return nil
+}
+
+// notify 唤醒一个空闲的发送任务，不等待
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 启动发送任务并定期清理过期的失败邮件，直到 ctx 结束
+func (q *Queue) Run(ctx context.Context) {
+	workers := q.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := q.repo.PurgeDead(time.Now().Add(-q.cfg.DeadRetention)); err != nil {
+			log.Printf("Failed to purge dead emails: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.RunPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// RunPending 发送所有到期的邮件，由发送任务定期调用
+func (q *Queue) RunPending(ctx context.Context) {
+	for {
+		now := time.Now()
+		ids, err := q.repo.ListDue(now, now.Add(-q.cfg.LockTimeout), queueBatchSize)
+		if err != nil {
+			log.Printf("Failed to list queued emails: %v", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return
+			}
+			q.deliver(id)
+		}
+		if len(ids) < queueBatchSize {
+			return
+		}
+	}
+}
+
+// deliver 发送一封邮件，同一封邮件只会被一个调用方发送
+func (q *Queue) deliver(id uint) {
+	now := time.Now()
+	claimed, err := q.repo.Claim(id, now, now.Add(-q.cfg.LockTimeout))
+	if err != nil || !claimed {
+		return
+	}
+	email, err := q.repo.Get(id)
+	if err != nil {
+		return
+	}
+
+	msg := &Message{
+		From:     email.From,
+		To:       email.To,
+		Subject:  email.Subject,
+		HTMLBody: email.HTMLBody,
+		TextBody: email.TextBody,
+	}
+	if email.Headers != "" {
+		if err := json.Unmarshal([]byte(email.Headers), &msg.Headers); err != nil {
+			log.Printf("Failed to decode headers of queued email %d: %v", id, err)
+		}
+	}
+	sendErr := q.transport.Send(msg)
+	if sendErr == nil {
+		if err := q.repo.Delete(id); err != nil {
+			log.Printf("Failed to remove sent email %d from queue: %v", id, err)
+		}
+		return
+	}
+
+	attempts := email.Attempts + 1
+	fields := map[string]interface{}{
+		"attempts":   attempts,
+		"locked_at":  nil,
+		"last_error": truncateError(sendErr),
+	}
+	if attempts >= q.cfg.MaxAttempts {
+		fields["status"] = models.QueuedEmailDead
+		log.Printf("Email %d to %s failed after %d attempts: %v", id, email.To, attempts, sendErr)
+	} else {
+		fields["status"] = models.QueuedEmailPending
+		fields["next_attempt_at"] = time.Now().Add(q.backoff(attempts))
+	}
+	if err := q.repo.Update(id, fields); err != nil {
+		log.Printf("Failed to update queued email %d: %v", id, err)
+	}
+}
+
+// backoff 第 attempts 次失败后的等待时长：BaseBackoff * 2^(attempts-1)，不超过 MaxBackoff
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= q.cfg.MaxBackoff {
+			return q.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Stats 返回队列状态和最近的 limit 封失败邮件
+func (q *Queue) Stats(limit int) (*QueueStats, error) {
+	counts, err := q.repo.CountByStatus()
+	if err != nil {
+		return nil, errors.New("获取发送队列失败")
+	}
+	oldest, err := q.repo.OldestPending()
+	if err != nil {
+		return nil, errors.New("获取发送队列失败")
+	}
+	dead, err := q.repo.ListDead(limit)
+	if err != nil {
+		return nil, errors.New("获取发送队列失败")
+	}
+
+	return &QueueStats{
+		Pending:         counts[models.QueuedEmailPending],
+		Sending:         counts[models.QueuedEmailSending],
+		Dead:            counts[models.QueuedEmailDead],
+		OldestPendingAt: oldest,
+		DeadLetters:     dead,
+	}, nil
+}
+
+// Retry 将失败列表中的邮件重新放回队列，重新计算发送次数
+func (q *Queue) Retry(id uint) error {
+	ok, err := q.repo.Retry(id, time.Now())
+	if err != nil {
+		return errors.New("重发邮件失败")
+	}
+	if !ok {
+		return ErrQueuedEmailNotFound
+	}
+	q.notify()
+	return nil
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > maxLastErrorLength {
+		msg = strings.ToValidUTF8(msg[:maxLastErrorLength], "")
+	}
+	return msg
+}
@@ -0,0 +1,55 @@
+package email
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueueHandler 处理邮件发送队列的管理接口
+type QueueHandler struct {
+	queue *Queue
+}
+
+// NewQueueHandler 创建新的 QueueHandler 实例
+func NewQueueHandler(queue *Queue) *QueueHandler {
+	return &QueueHandler{queue: queue}
+}
+
+// GetQueue 查看队列积压数量和最近失败的邮件
+func (h *QueueHandler) GetQueue(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	stats, err := h.queue.Stats(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// RetryEmail 重新发送失败列表中的邮件
+func (h *QueueHandler) RetryEmail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的邮件ID"})
+		return
+	}
+
+	if err := h.queue.Retry(uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrQueuedEmailNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "邮件已重新加入发送队列"})
+}
@@ -0,0 +1,131 @@
+package email
+
+import (
+	"time"
+
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// QueueRepository 邮件发送队列的数据访问
+type QueueRepository struct {
+	db *gorm.DB
+}
+
+func NewQueueRepository(db *gorm.DB) *QueueRepository {
+	return &QueueRepository{db: db}
+}
+
+// Enqueue 将邮件加入队列
+func (r *QueueRepository) Enqueue(email *models.QueuedEmail) error {
+	return r.db.Create(email).Error
+}
+
+// ListDue 获取到期待发送的邮件ID，包括领取后超时未完成的邮件
+func (r *QueueRepository) ListDue(now, staleBefore time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.QueuedEmail{}).
+		Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+			models.QueuedEmailPending, now, models.QueuedEmailSending, staleBefore).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Claim 领取邮件并标记为发送中，返回 false 表示已被其他进程领取
+func (r *QueueRepository) Claim(id uint, now, staleBefore time.Time) (bool, error) {
+	result := r.db.Model(&models.QueuedEmail{}).
+		Where("id = ? AND ((status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?))",
+			id, models.QueuedEmailPending, now, models.QueuedEmailSending, staleBefore).
+		Updates(map[string]interface{}{
+			"status":    models.QueuedEmailSending,
+			"locked_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Get 获取队列中的邮件
+func (r *QueueRepository) Get(id uint) (*models.QueuedEmail, error) {
+	var email models.QueuedEmail
+	if err := r.db.First(&email, id).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// Update 更新邮件的指定字段
+func (r *QueueRepository) Update(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.QueuedEmail{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Delete 从队列中删除邮件（发送成功后调用）
+func (r *QueueRepository) Delete(id uint) error {
+	return r.db.Unscoped().Delete(&models.QueuedEmail{}, id).Error
+}
+
+// Retry 将失败的邮件重新放回队列，返回 false 表示邮件不存在或不在失败列表中
+func (r *QueueRepository) Retry(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&models.QueuedEmail{}).
+		Where("id = ? AND status = ?", id, models.QueuedEmailDead).
+		Updates(map[string]interface{}{
+			"status":          models.QueuedEmailPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"locked_at":       nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountByStatus 按状态统计队列中的邮件数量
+func (r *QueueRepository) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&models.QueuedEmail{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// OldestPending 获取最早入队且尚未发送的邮件的入队时间，队列为空时返回 nil
+func (r *QueueRepository) OldestPending() (*time.Time, error) {
+	var email models.QueuedEmail
+	err := r.db.Select("created_at").
+		Where("status IN ?", []string{models.QueuedEmailPending, models.QueuedEmailSending}).
+		Order("created_at ASC").
+		Limit(1).
+		Find(&email).Error
+	if err != nil || email.CreatedAt.IsZero() {
+		return nil, err
+	}
+	return &email.CreatedAt, nil
+}
+
+// ListDead 获取失败列表，最近失败的在前
+func (r *QueueRepository) ListDead(limit int) ([]*models.QueuedEmail, error) {
+	var emails []*models.QueuedEmail
+	err := r.db.Where("status = ?", models.QueuedEmailDead).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&emails).Error
+	return emails, err
+}
+
+// PurgeDead 删除失败时间早于 before 的邮件
+func (r *QueueRepository) PurgeDead(before time.Time) error {
+	return r.db.Unscoped().
+		Where("status = ? AND updated_at < ?", models.QueuedEmailDead, before).
+		Delete(&models.QueuedEmail{}).Error
+}
@@ -0,0 +1,117 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestQueueBackoff(t *testing.T) {
+	q := &Queue{cfg: configs.MailQueueConfig{BaseBackoff: time.Minute, MaxBackoff: time.Hour}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// failingTransport 前 failures 次发送失败，之后成功
+type failingTransport struct {
+	failures int
+	sent     int
+}
+
+func (t *failingTransport) Send(msg *Message) error {
+	if t.failures > 0 {
+		t.failures--
+		return errors.New("smtp: connection refused")
+	}
+	t.sent++
+	return nil
+}
+
+func TestQueueRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // 传输层前几次发送失败
+		rounds       int // 执行 RunPending 的轮数，每轮之间把重试时间提前以模拟等待
+		wantQueued   bool
+		wantStatus   string
+		wantAttempts int
+	}{
+		{"首次成功后删除", 0, 1, false, "", 0},
+		{"失败后等待重试", 1, 1, true, models.QueuedEmailPending, 1},
+		{"重试成功后删除", 1, 2, false, "", 0},
+		{"达到最多发送次数后不再重试", 5, 4, true, models.QueuedEmailDead, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("open db: %v", err)
+			}
+			if err := db.AutoMigrate(&models.QueuedEmail{}); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			transport := &failingTransport{failures: tt.failures}
+			q := NewQueue(NewQueueRepository(db), transport, configs.MailQueueConfig{
+				MaxAttempts: 3,
+				BaseBackoff: time.Minute,
+				MaxBackoff:  time.Hour,
+				LockTimeout: time.Minute,
+			})
+
+			if err := q.Send(&Message{To: "a@example.com", Subject: "hi", TextBody: "body"}); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			for i := 0; i < tt.rounds; i++ {
+				if i > 0 {
+					db.Model(&models.QueuedEmail{}).Where("status = ?", models.QueuedEmailPending).
+						Update("next_attempt_at", time.Now().Add(-time.Second))
+				}
+				q.RunPending(context.Background())
+			}
+
+			var emails []models.QueuedEmail
+			db.Find(&emails)
+			if !tt.wantQueued {
+				if len(emails) != 0 || transport.sent != 1 {
+					t.Fatalf("sent = %d, queued = %d, want sent once and removed", transport.sent, len(emails))
+				}
+				return
+			}
+			if len(emails) != 1 {
+				t.Fatalf("queued = %d, want 1", len(emails))
+			}
+			email := emails[0]
+			if email.Status != tt.wantStatus || email.Attempts != tt.wantAttempts || email.LastError == "" || email.LockedAt != nil {
+				t.Errorf("got status %s, attempts %d, last error %q, locked %v; want %s after %d attempts",
+					email.Status, email.Attempts, email.LastError, email.LockedAt, tt.wantStatus, tt.wantAttempts)
+			}
+			if tt.wantStatus == models.QueuedEmailPending {
+				if wait := time.Until(email.NextAttemptAt); wait < 50*time.Second || wait > time.Minute {
+					t.Errorf("next attempt in %v, want about 1m", wait)
+				}
+			}
+		})
+	}
+}
@@ -2,59 +2,40 @@ package email
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"go-tree-hollow/configs"
 	"time"
 
-	"github.com/go-redis/redis/v8"
+	"go-tree-hollow/pkg/kv"
 )
 
-func NewClient(cfg *configs.RedisConfig) (*redis.Client, error) {
-	fmt.Println(cfg.Password, 1)
-	client := redis.NewClient(&redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdleConns,
-		MaxRetries:   cfg.MaxRetries,
-		DialTimeout:  cfg.DialTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-	})
-
-	// 测试连接
-	ctx := context.Background()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("redis 连接失败: %w", err)
-	}
-
-	return client, nil
-}
-
+// CodeRepository 存储验证码，键按用途区分：<prefix>:<类型>:<用途>:<邮箱>，
+// 不同用途的验证码互不通用
 type CodeRepository struct {
-	client *redis.Client
+	store  kv.Store
 	prefix string
 }
 
-func NewCodeRepository(client *redis.Client, prefix string) *CodeRepository {
+func NewCodeRepository(store kv.Store, prefix string) *CodeRepository {
 	return &CodeRepository{
-		client: client,
+		store:  store,
 		prefix: prefix,
 	}
 }
 
-// Set 存储验证码
-func (r *CodeRepository) Set(ctx context.Context, email, code string, expire time.Duration) error {
-	key := r.buildKey(email)
-	return r.client.Set(ctx, key, code, expire).Err()
+// Set 存储验证码，并清空上一个验证码的错误次数
+func (r *CodeRepository) Set(ctx context.Context, purpose Purpose, email, code string, expire time.Duration) error {
+	if err := r.store.Set(ctx, r.buildKey("code", purpose, email), code, expire); err != nil {
+		return err
+	}
+	_, err := r.store.Del(ctx, r.buildKey("attempts", purpose, email))
+	return err
 }
 
 // Get 获取验证码
-func (r *CodeRepository) Get(ctx context.Context, email string) (string, error) {
-	key := r.buildKey(email)
-	code, err := r.client.Get(ctx, key).Result()
-	if err == redis.Nil {
+func (r *CodeRepository) Get(ctx context.Context, purpose Purpose, email string) (string, error) {
+	code, err := r.store.Get(ctx, r.buildKey("code", purpose, email))
+	if errors.Is(err, kv.ErrNotFound) {
 		return "", fmt.Errorf("验证码已过期或不存在")
 	}
 	if err != nil {
@@ -63,24 +44,32 @@ func (r *CodeRepository) Get(ctx context.Context, email string) (string, error)
 	return code, nil
 }
 
-// Delete 删除验证码
-func (r *CodeRepository) Delete(ctx context.Context, email string) error {
-	key := r.buildKey(email)
-	return r.client.Del(ctx, key).Err()
+// Delete 删除验证码及其错误次数，返回 false 表示验证码已不存在（已被使用或已失效）
+func (r *CodeRepository) Delete(ctx context.Context, purpose Purpose, email string) (bool, error) {
+	n, err := r.store.Del(ctx, r.buildKey("code", purpose, email))
+	if err != nil {
+		return false, err
+	}
+	r.store.Del(ctx, r.buildKey("attempts", purpose, email))
+	return n > 0, nil
+}
+
+// IncrAttempts 记录一次校验失败，返回当前验证码的累计错误次数
+func (r *CodeRepository) IncrAttempts(ctx context.Context, purpose Purpose, email string, expire time.Duration) (int64, error) {
+	return r.store.Incr(ctx, r.buildKey("attempts", purpose, email), expire)
 }
 
-// Exists 检查是否存在
-func (r *CodeRepository) Exists(ctx context.Context, email string) bool {
-	key := r.buildKey(email)
-	return r.client.Exists(ctx, key).Val() > 0
+// SetNX 设置防重发标记，返回 false 表示发送间隔内已发送过
+func (r *CodeRepository) SetNX(ctx context.Context, purpose Purpose, email string, value string, expire time.Duration) (bool, error) {
+	return r.store.SetNX(ctx, r.buildKey("lock", purpose, email), value, expire)
 }
 
-// SetNX 设置防重发标记
-func (r *CodeRepository) SetNX(ctx context.Context, email string, value string, expire time.Duration) (bool, error) {
-	key := fmt.Sprintf("%s:lock:%s", r.prefix, email)
-	return r.client.SetNX(ctx, key, value, expire).Result()
+// DeleteLock 清除防重发标记
+func (r *CodeRepository) DeleteLock(ctx context.Context, purpose Purpose, email string) error {
+	_, err := r.store.Del(ctx, r.buildKey("lock", purpose, email))
+	return err
 }
 
-func (r *CodeRepository) buildKey(email string) string {
-	return fmt.Sprintf("%s:code:%s", r.prefix, email)
+func (r *CodeRepository) buildKey(kind string, purpose Purpose, email string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", r.prefix, kind, purpose, email)
 }
@@ -3,10 +3,19 @@ package email
 import "github.com/gin-gonic/gin"
 
 // RegisterRoutes 注册认证模块路由
-func RegisterRoutes(router *gin.RouterGroup, handler *EmailHandler) {
+// adminMiddleware 用于限制只有管理员才能查看发送队列
+func RegisterRoutes(router *gin.RouterGroup, handler *EmailHandler, queueHandler *QueueHandler, authMiddleware, adminMiddleware gin.HandlerFunc) {
 	// 创建 /auth 子路由组
 	authGroup := router.Group("/email")
 	{
 		authGroup.POST("/send", handler.SendVerificationCode)
 	}
+
+	// 发送队列管理（仅管理员）
+	adminGroup := router.Group("/admin/email")
+	adminGroup.Use(authMiddleware, adminMiddleware)
+	{
+		adminGroup.GET("/queue", queueHandler.GetQueue)              // GET /api/v1/admin/email/queue - 查看队列积压和失败的邮件
+		adminGroup.POST("/queue/:id/retry", queueHandler.RetryEmail) // POST /api/v1/admin/email/queue/:id/retry - 重发失败的邮件
+	}
 }
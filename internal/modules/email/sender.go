@@ -0,0 +1,72 @@
+package email
+
+import (
+	"fmt"
+)
+
+type EmailConfig struct {
+	SMTPHost    string
+	SMTPPort    int
+	Username    string
+	Password    string
+	From        string
+	Secure      bool
+	Transport   string
+	OutboxDir   string
+	TemplateDir string
+}
+
+// Sender 组装邮件并交给 Transport 发送
+type Sender struct {
+	from      string
+	transport Transport
+	templates *Templates
+}
+
+func NewSender(from string, transport Transport, templates *Templates) *Sender {
+	return &Sender{
+		from:      from,
+		transport: transport,
+		templates: templates,
+	}
+}
+
+// Send 发送邮件
+func (s *Sender) Send(to, subject, body string, contentType ...string) error {
+	msg := &Message{To: to, Subject: subject}
+
+	// 默认使用 HTML 格式
+	if len(contentType) > 0 && contentType[0] == "text/plain" {
+		msg.TextBody = body
+	} else {
+		msg.HTMLBody = body
+	}
+	return s.SendMessage(msg)
+}
+
+// SendMessage 发送邮件，未指定发件人时使用配置的发件人
+func (s *Sender) SendMessage(msg *Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	if err := s.transport.Send(msg); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// SendTemplate 使用指定语言的模板渲染并发送邮件，locale 为空或不支持时使用默认语言
+func (s *Sender) SendTemplate(to, locale, name string, data interface{}) error {
+	return s.SendTemplateWithHeaders(to, locale, name, nil, data)
+}
+
+// SendTemplateWithHeaders 与 SendTemplate 相同，并附加额外的邮件头
+func (s *Sender) SendTemplateWithHeaders(to, locale, name string, headers map[string]string, data interface{}) error {
+	msg, err := s.templates.Render(name, locale, data)
+	if err != nil {
+		return fmt.Errorf("渲染邮件失败: %w", err)
+	}
+	msg.To = to
+	msg.Headers = headers
+	return s.SendMessage(msg)
+}
@@ -2,27 +2,50 @@ package email
 
 import (
 	"context"
-	"crypto/tls"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"go-tree-hollow/configs"
 	"go-tree-hollow/pkg/utils"
-	"math/rand"
-	"time"
-
-	"gopkg.in/gomail.v2"
 )
 
 var (
-	ErrInvalidEmail    = errors.New("邮箱格式不正确")
-	ErrCodeSendTooFast = errors.New("发送过于频繁，请稍后再试")
-	ErrCodeExpired     = errors.New("验证码已过期")
-	ErrCodeInvalid     = errors.New("验证码错误")
+	ErrInvalidEmail         = errors.New("邮箱格式不正确")
+	ErrCodeSendTooFast      = errors.New("发送过于频繁，请稍后再试")
+	ErrCodeExpired          = errors.New("验证码已过期")
+	ErrCodeInvalid          = errors.New("验证码错误")
+	ErrCodeAttemptsExceeded = errors.New("验证码错误次数过多，请重新获取")
+)
+
+// Purpose 验证码的用途，不同用途的验证码分开存储，互不通用
+type Purpose string
+
+const (
+	PurposeRegister      Purpose = "register"
+	PurposeLogin         Purpose = "login"
+	PurposeResetPassword Purpose = "reset"
+	PurposeChangeEmail   Purpose = "change_email"
+	PurposeReauth        Purpose = "reauth" // 敏感操作前确认身份，发送到用户当前的邮箱
 )
 
+// purposeTemplates 每种用途使用的邮件模板
+var purposeTemplates = map[Purpose]string{
+	PurposeRegister:      TemplateVerification,
+	PurposeLogin:         TemplateVerification,
+	PurposeResetPassword: TemplatePasswordReset,
+	PurposeChangeEmail:   TemplateVerification,
+	PurposeReauth:        TemplateVerification,
+}
+
 type EmailService interface {
-	SendVerificationCode(ctx context.Context, email string) error
-	VerifyCode(ctx context.Context, email, code string) error
+	// SendVerificationCode 生成指定用途的验证码并按 locale 语言发送到邮箱
+	SendVerificationCode(ctx context.Context, purpose Purpose, email, locale string) error
+	// SendVerificationCodeTo 与 SendVerificationCode 相同，但验证码按 subject 而不是收件邮箱存储，
+	// 校验时 VerifyCode 需要传入同一个 subject，用于把验证码绑定到发起请求的用户
+	SendVerificationCodeTo(ctx context.Context, purpose Purpose, subject, email, locale string) error
+	// VerifyCode 校验指定用途的验证码，email 为存储验证码时使用的邮箱或 subject，校验通过后验证码失效；
+	// 连续错误达到 CodeConfig.MaxAttempts 次后验证码同样失效，需要重新获取
+	VerifyCode(ctx context.Context, purpose Purpose, email, code string) error
 }
 
 type emailServiceImpl struct {
@@ -40,149 +63,91 @@ func NewEmailService(sender *Sender, codeRepo *CodeRepository, cfg *configs.Conf
 }
 
 // SendVerificationCode 发送验证码
-func (s *emailServiceImpl) SendVerificationCode(ctx context.Context, email string) error {
+func (s *emailServiceImpl) SendVerificationCode(ctx context.Context, purpose Purpose, email, locale string) error {
+	return s.SendVerificationCodeTo(ctx, purpose, email, email, locale)
+}
+
+// SendVerificationCodeTo 发送验证码，验证码和发送频率限制都按 subject 存储
+func (s *emailServiceImpl) SendVerificationCodeTo(ctx context.Context, purpose Purpose, subject, email, locale string) error {
+	template, ok := purposeTemplates[purpose]
+	if !ok {
+		return fmt.Errorf("未知的验证码用途: %s", purpose)
+	}
+
 	// 1. 验证邮箱格式
 	if !utils.ValidateEmail(email) {
 		return ErrInvalidEmail
 	}
 
 	// 2. 检查发送频率（1分钟内只能发送一次）
-	lockKey := fmt.Sprintf("lock:%s", email)
-	if exists, _ := s.codeRepo.SetNX(ctx, email, "1", s.cfg.Code.SendInterval); !exists {
+	if exists, _ := s.codeRepo.SetNX(ctx, purpose, subject, "1", s.cfg.Code.SendInterval); !exists {
 		return ErrCodeSendTooFast
 	}
 
 	// 3. 生成验证码
-	code := utils.GenerateCode(s.cfg.Code.Length)
-
-	// 4. 发送邮件
-	if err := s.sender.SendVerificationCode(email, code); err != nil {
-		// 发送失败，清除频率限制
-		s.codeRepo.Delete(ctx, lockKey)
-		return fmt.Errorf("邮件发送失败: %w", err)
+	code, err := utils.GenerateCode(s.cfg.Code.Length)
+	if err != nil {
+		s.codeRepo.DeleteLock(ctx, purpose, subject)
+		return fmt.Errorf("生成验证码失败: %w", err)
 	}
 
-	// 5. 存储验证码到Redis
-	if err := s.codeRepo.Set(ctx, email, code, s.cfg.Code.ExpireTime); err != nil {
+	// 4. 先存储验证码再发送，避免用户收到邮件时验证码尚未生效
+	if err := s.codeRepo.Set(ctx, purpose, subject, code, s.cfg.Code.ExpireTime); err != nil {
+		s.codeRepo.DeleteLock(ctx, purpose, subject)
 		return fmt.Errorf("存储验证码失败: %w", err)
 	}
 
+	// 5. 发送邮件
+	data := &VerificationData{
+		Code:          code,
+		ExpireMinutes: int(s.cfg.Code.ExpireTime.Minutes()),
+	}
+	if err := s.sender.SendTemplate(email, locale, template, data); err != nil {
+		// 发送失败，删除未送达的验证码并清除频率限制
+		s.codeRepo.Delete(ctx, purpose, subject)
+		s.codeRepo.DeleteLock(ctx, purpose, subject)
+		return fmt.Errorf("邮件发送失败: %w", err)
+	}
+
 	return nil
 }
 
 // VerifyCode 验证验证码
-func (s *emailServiceImpl) VerifyCode(ctx context.Context, email, code string) error {
+func (s *emailServiceImpl) VerifyCode(ctx context.Context, purpose Purpose, email, code string) error {
 	// 1. 获取存储的验证码
-	storedCode, err := s.codeRepo.Get(ctx, email)
+	storedCode, err := s.codeRepo.Get(ctx, purpose, email)
 	if err != nil {
 		return ErrCodeExpired
 	}
 
-	// 2. 比对验证码
-	if storedCode != code {
+	// 2. 比对验证码（固定时间比较），错误次数达到上限后验证码失效
+	if subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) != 1 {
+		attempts, err := s.codeRepo.IncrAttempts(ctx, purpose, email, s.cfg.Code.ExpireTime)
+		if err != nil {
+			return ErrCodeInvalid
+		}
+		if attempts >= int64(s.cfg.Code.MaxAttempts) {
+			s.codeRepo.Delete(ctx, purpose, email)
+			return ErrCodeAttemptsExceeded
+		}
 		return ErrCodeInvalid
 	}
 
-	// 3. 验证成功，删除验证码（防止重复使用）
-	s.codeRepo.Delete(ctx, email)
+	// 3. 验证成功，删除验证码（防止重复使用），并发请求中只有一个能成功
+	if consumed, err := s.codeRepo.Delete(ctx, purpose, email); err != nil || !consumed {
+		return ErrCodeExpired
+	}
 
 	return nil
 }
 
-// GenerateCode 生成随机验证码
-func GenerateCode(length int) string {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	const digits = "0123456789"
-	code := make([]byte, length)
-	for i := range code {
-		code[i] = digits[rand.Intn(len(digits))]
-	}
-	return string(code)
-}
-
 type SendCodeRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email   string  `json:"email" binding:"required,email"`
+	Purpose Purpose `json:"purpose" binding:"required,oneof=register login"` // 注册或验证码登录，重置密码和更换邮箱使用各自的接口
+	Locale  string  `json:"locale"`                                          // 邮件语言（zh-CN / en），为空时根据 Accept-Language 请求头判断
 }
 
 // SendCodeResponse 响应结构
 type SendCodeResponse struct {
 	Message string `json:"message"`
 }
-
-type Sender struct {
-	config *EmailConfig
-	dialer *gomail.Dialer
-}
-
-type EmailConfig struct {
-	SMTPHost string
-	SMTPPort int
-	Username string
-	Password string
-	From     string
-	Secure   bool
-}
-
-func NewSender(config *EmailConfig) *Sender {
-	fmt.Print(config.Password)
-	dialer := gomail.NewDialer(config.SMTPHost, config.SMTPPort, config.Username, config.Password)
-
-	// SSL/TLS 配置
-	if config.Secure {
-		dialer.TLSConfig = &tls.Config{
-			ServerName:         config.SMTPHost,
-			InsecureSkipVerify: false}
-	}
-
-	return &Sender{
-		config: config,
-		dialer: dialer,
-	}
-}
-
-// Send 发送邮件
-func (s *Sender) Send(to, subject, body string, contentType ...string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", s.config.From)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-
-	// 默认使用 HTML 格式
-	ct := "text/html"
-	if len(contentType) > 0 {
-		ct = contentType[0]
-	}
-	m.SetBody(ct, body)
-
-	if err := s.dialer.DialAndSend(m); err != nil {
-		return fmt.Errorf("发送邮件失败: %w", err)
-	}
-	return nil
-}
-
-// SendVerificationCode 发送验证码邮件（使用模板）
-func (s *Sender) SendVerificationCode(to, code string) error {
-	subject := fmt.Sprintf("验证码 - %s", code)
-
-	// HTML 模板（可提取到单独文件）
-	body := fmt.Sprintf(`
-    <!DOCTYPE html>
-    <html>
-    <head>
-        <meta charset="UTF-8">
-        <title>验证码</title>
-    </head>
-    <body>
-        <div style="padding: 20px;">
-            <h2>您的验证码</h2>
-            <p>验证码：<strong style="color: #1890ff; font-size: 24px;">%s</strong></p>
-            <p>有效期：5分钟，请勿泄露给他人</p>
-            <p>如非本人操作，请忽略此邮件</p>
-        </div>
-    </body>
-    </html>
-    `, code)
-
-	return s.Send(to, subject, body)
-}
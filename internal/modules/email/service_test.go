@@ -0,0 +1,158 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/pkg/kv"
+)
+
+func newTestEmailService(t *testing.T) (*emailServiceImpl, *MemoryTransport) {
+	t.Helper()
+	templates, err := LoadTemplates("../../../templates/email", "树洞")
+	if err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	transport := NewMemoryTransport()
+	cfg := &configs.Config{Code: configs.CodeConfig{
+		Length:       6,
+		ExpireTime:   5 * time.Minute,
+		SendInterval: time.Minute,
+		MaxAttempts:  3,
+	}}
+	service := NewEmailService(NewSender("noreply@example.com", transport, templates), NewCodeRepository(kv.NewMemoryStore(), "test"), cfg)
+	return service.(*emailServiceImpl), transport
+}
+
+var codePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// sentCode 从最近一封邮件的正文中取出验证码
+func sentCode(t *testing.T, transport *MemoryTransport) string {
+	t.Helper()
+	messages := transport.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no email sent")
+	}
+	code := codePattern.FindString(messages[len(messages)-1].TextBody)
+	if code == "" {
+		t.Fatalf("no code in email: %s", messages[len(messages)-1].TextBody)
+	}
+	return code
+}
+
+func TestVerifyCodeAttemptLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		wrong      int   // 先提交错误验证码的次数
+		wantLast   error // 最后一次提交错误验证码的结果
+		wantStatus error // 之后提交正确验证码的结果
+	}{
+		{"没有错误", 0, nil, nil},
+		{"未达到上限", 2, ErrCodeInvalid, nil},
+		{"达到上限后失效", 3, ErrCodeAttemptsExceeded, ErrCodeExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			service, transport := newTestEmailService(t)
+			if err := service.SendVerificationCode(ctx, PurposeLogin, "a@example.com", "zh-CN"); err != nil {
+				t.Fatalf("SendVerificationCode: %v", err)
+			}
+			code := sentCode(t, transport)
+
+			for i := 0; i < tt.wrong; i++ {
+				err := service.VerifyCode(ctx, PurposeLogin, "a@example.com", wrongCode(code))
+				if i == tt.wrong-1 && !errors.Is(err, tt.wantLast) {
+					t.Fatalf("wrong attempt %d error = %v, want %v", i+1, err, tt.wantLast)
+				}
+			}
+			if err := service.VerifyCode(ctx, PurposeLogin, "a@example.com", code); !errors.Is(err, tt.wantStatus) {
+				t.Errorf("VerifyCode with correct code error = %v, want %v", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeIsPurposeScoped(t *testing.T) {
+	ctx := context.Background()
+	service, transport := newTestEmailService(t)
+
+	if err := service.SendVerificationCode(ctx, PurposeLogin, "a@example.com", "en"); err != nil {
+		t.Fatalf("SendVerificationCode(login): %v", err)
+	}
+	loginCode := sentCode(t, transport)
+	if err := service.SendVerificationCode(ctx, PurposeRegister, "a@example.com", "en"); err != nil {
+		t.Fatalf("SendVerificationCode(register): %v", err)
+	}
+	registerCode := sentCode(t, transport)
+
+	// 用一种用途的验证码校验另一种用途，计入的是后者的错误次数
+	if loginCode != registerCode {
+		if err := service.VerifyCode(ctx, PurposeRegister, "a@example.com", loginCode); !errors.Is(err, ErrCodeInvalid) {
+			t.Fatalf("login code accepted for register: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		service.VerifyCode(ctx, PurposeRegister, "a@example.com", wrongCode(registerCode))
+	}
+	if err := service.VerifyCode(ctx, PurposeRegister, "a@example.com", registerCode); !errors.Is(err, ErrCodeExpired) {
+		t.Errorf("register code after exceeding attempts error = %v, want ErrCodeExpired", err)
+	}
+
+	// 注册验证码的错误次数不影响登录验证码
+	if err := service.VerifyCode(ctx, PurposeLogin, "a@example.com", loginCode); err != nil {
+		t.Errorf("login code error = %v, want nil", err)
+	}
+	// 验证通过后验证码失效
+	if err := service.VerifyCode(ctx, PurposeLogin, "a@example.com", loginCode); !errors.Is(err, ErrCodeExpired) {
+		t.Errorf("reused login code error = %v, want ErrCodeExpired", err)
+	}
+}
+
+func TestSendVerificationCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		purpose Purpose
+		email   string
+		wantErr error
+	}{
+		{"注册", PurposeRegister, "a@example.com", nil},
+		{"重置密码", PurposeResetPassword, "a@example.com", nil},
+		{"邮箱格式错误", PurposeLogin, "not-an-email", ErrInvalidEmail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			service, _ := newTestEmailService(t)
+			if err := service.SendVerificationCode(ctx, tt.purpose, tt.email, "zh-CN"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendVerificationCode error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// 发送间隔内同一用途不能再次发送，其他用途不受影响
+			if err := service.SendVerificationCode(ctx, tt.purpose, tt.email, "zh-CN"); !errors.Is(err, ErrCodeSendTooFast) {
+				t.Errorf("second send error = %v, want ErrCodeSendTooFast", err)
+			}
+			if err := service.SendVerificationCode(ctx, PurposeChangeEmail, tt.email, "zh-CN"); err != nil {
+				t.Errorf("send for another purpose error = %v, want nil", err)
+			}
+		})
+	}
+
+	service, _ := newTestEmailService(t)
+	if err := service.SendVerificationCode(context.Background(), Purpose("unknown"), "a@example.com", "zh-CN"); err == nil {
+		t.Error("SendVerificationCode with unknown purpose should fail")
+	}
+}
+
+// wrongCode 返回与 code 不同的同长度验证码
+func wrongCode(code string) string {
+	b := []byte(code)
+	b[0] = '0' + (b[0]-'0'+1)%10
+	return string(b)
+}
@@ -0,0 +1,182 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// 支持的语言，模板目录下每种语言一个子目录
+const (
+	LocaleZhCN    = "zh-CN"
+	LocaleEn      = "en"
+	DefaultLocale = LocaleZhCN
+)
+
+// 邮件模板名称，每个模板由 <名称>.html 和 <名称>.txt 组成，
+// .txt 中通过 {{define "subject"}} 定义邮件标题
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateSecurityAlert = "security_alert"
+	TemplateDigest        = "digest"
+)
+
+var templateNames = []string{TemplateVerification, TemplatePasswordReset, TemplateSecurityAlert, TemplateDigest}
+
+// 安全提醒的事件类型
+const (
+	AlertAccountLocked   = "account_locked"
+	AlertPasswordChanged = "password_changed"
+	AlertEmailChanged    = "email_changed"
+)
+
+// VerificationData 验证码邮件（注册、登录、更换邮箱、重置密码）的模板数据
+type VerificationData struct {
+	Code          string
+	ExpireMinutes int
+}
+
+// SecurityAlertData 账号安全提醒的模板数据
+type SecurityAlertData struct {
+	Event    string // AlertAccountLocked 等
+	Time     string
+	IP       string // 触发事件的IP，可为空
+	NewEmail string // 更换后的邮箱（已脱敏），仅 AlertEmailChanged 使用
+}
+
+// DigestData 每周动态摘要的模板数据
+type DigestData struct {
+	Nickname       string
+	PeriodStart    string
+	PeriodEnd      string
+	NewLikes       int64
+	NewComments    int64
+	NewFollowers   int64
+	UnreadMessages int64
+	UnsubscribeURL string
+}
+
+// Templates 按语言加载的邮件模板
+type Templates struct {
+	locales map[string]map[string]*mailTemplate
+}
+
+type mailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// IsSupportedLocale 判断是否为支持的语言
+func IsSupportedLocale(locale string) bool {
+	return locale == LocaleZhCN || locale == LocaleEn
+}
+
+// NormalizeLocale 将客户端提供的语言（如 en-US、zh、Accept-Language 请求头）转换为支持的语言，
+// 无法识别时返回默认语言
+func NormalizeLocale(locale string) string {
+	// Accept-Language 取第一个语言，如 "en-US,en;q=0.9"
+	if i := strings.IndexAny(locale, ",;"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	switch {
+	case strings.HasPrefix(locale, "en"):
+		return LocaleEn
+	default:
+		return DefaultLocale
+	}
+}
+
+// LoadTemplates 从 dir/<语言>/ 加载全部邮件模板，默认语言必须包含所有模板，
+// 其他语言缺少的模板回退到默认语言。appName 可在模板中通过 {{app}} 引用。
+func LoadTemplates(dir, appName string) (*Templates, error) {
+	funcs := map[string]interface{}{
+		"app": func() string { return appName },
+	}
+
+	t := &Templates{locales: make(map[string]map[string]*mailTemplate)}
+	for _, locale := range []string{LocaleZhCN, LocaleEn} {
+		localeDir := filepath.Join(dir, locale)
+		if _, err := os.Stat(localeDir); err != nil {
+			if locale == DefaultLocale {
+				return nil, fmt.Errorf("加载邮件模板失败: %w", err)
+			}
+			continue
+		}
+
+		set := make(map[string]*mailTemplate)
+		for _, name := range templateNames {
+			tmpl, err := loadTemplate(localeDir, name, funcs)
+			if err != nil {
+				if os.IsNotExist(err) && locale != DefaultLocale {
+					continue
+				}
+				return nil, fmt.Errorf("加载邮件模板 %s/%s 失败: %w", locale, name, err)
+			}
+			set[name] = tmpl
+		}
+		t.locales[locale] = set
+	}
+	return t, nil
+}
+
+func loadTemplate(dir, name string, funcs map[string]interface{}) (*mailTemplate, error) {
+	htmlFile := filepath.Join(dir, name+".html")
+	textFile := filepath.Join(dir, name+".txt")
+	for _, file := range []string{htmlFile, textFile} {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+	}
+
+	// HTML 正文套用同目录下的 layout.html，模板内通过 {{define "content"}} 提供正文
+	html, err := htmltemplate.New("layout.html").Funcs(funcs).ParseFiles(filepath.Join(dir, "layout.html"), htmlFile)
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New(name + ".txt").Funcs(funcs).ParseFiles(textFile)
+	if err != nil {
+		return nil, err
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("%s 缺少 subject 定义", textFile)
+	}
+	return &mailTemplate{html: html, text: text}, nil
+}
+
+// Render 使用指定语言渲染邮件，生成标题、HTML 正文和纯文本正文
+func (t *Templates) Render(name, locale string, data interface{}) (*Message, error) {
+	tmpl := t.lookup(name, NormalizeLocale(locale))
+	if tmpl == nil {
+		return nil, fmt.Errorf("邮件模板 %s 不存在", name)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject:  strings.TrimSpace(subject.String()),
+		HTMLBody: html.String(),
+		TextBody: strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+func (t *Templates) lookup(name, locale string) *mailTemplate {
+	if tmpl, ok := t.locales[locale][name]; ok {
+		return tmpl
+	}
+	return t.locales[DefaultLocale][name]
+}
@@ -0,0 +1,169 @@
+package email
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Message 一封待发送的邮件，HTMLBody 和 TextBody 至少提供一个，同时提供时作为多格式邮件发送
+type Message struct {
+	From     string
+	To       string
+	Subject  string
+	HTMLBody string
+	TextBody string
+	Headers  map[string]string // 额外的邮件头，如 List-Unsubscribe
+}
+
+// Transport 邮件的投递方式
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// NewTransport 根据配置创建投递方式：smtp（默认）、file 或 log
+func NewTransport(config *EmailConfig) (Transport, error) {
+	switch config.Transport {
+	case "", "smtp":
+		return NewSMTPTransport(config), nil
+	case "file":
+		return NewFileTransport(config.OutboxDir)
+	case "log":
+		return NewLogTransport(slog.Default()), nil
+	default:
+		return nil, fmt.Errorf("不支持的邮件发送方式: %s", config.Transport)
+	}
+}
+
+// SMTPTransport 通过 SMTP 服务器发送邮件
+type SMTPTransport struct {
+	dialer *gomail.Dialer
+}
+
+func NewSMTPTransport(config *EmailConfig) *SMTPTransport {
+	dialer := gomail.NewDialer(config.SMTPHost, config.SMTPPort, config.Username, config.Password)
+
+	// SSL/TLS 配置
+	if config.Secure {
+		dialer.TLSConfig = &tls.Config{
+			ServerName:         config.SMTPHost,
+			InsecureSkipVerify: false}
+	}
+
+	return &SMTPTransport{dialer: dialer}
+}
+
+func (t *SMTPTransport) Send(msg *Message) error {
+	return t.dialer.DialAndSend(buildMessage(msg))
+}
+
+// FileTransport 将邮件写入目录中的 .eml 文件，可直接用邮件客户端打开，用于开发和测试环境
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建邮件目录失败: %w", err)
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), hex.EncodeToString(suffix))
+
+	// 先写入临时文件再改名，读取方不会看到写了一半的邮件
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := buildMessage(msg).WriteTo(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(t.dir, name))
+}
+
+// LogTransport 将邮件内容输出到结构化日志，不实际发送
+type LogTransport struct {
+	logger *slog.Logger
+}
+
+func NewLogTransport(logger *slog.Logger) *LogTransport {
+	return &LogTransport{logger: logger}
+}
+
+func (t *LogTransport) Send(msg *Message) error {
+	t.logger.Info("email",
+		"from", msg.From,
+		"to", msg.To,
+		"subject", msg.Subject,
+		"headers", msg.Headers,
+		"text", msg.TextBody,
+		"html", msg.HTMLBody,
+	)
+	return nil
+}
+
+// MemoryTransport 将邮件保存在内存中，供测试检查发送的内容
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, *msg)
+	return nil
+}
+
+// Messages 返回已发送邮件的副本，按发送顺序排列
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+// buildMessage 将 Message 转换为 MIME 邮件，同时有纯文本和 HTML 时纯文本在前
+func buildMessage(msg *Message) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", msg.From)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	for name, value := range msg.Headers {
+		m.SetHeader(name, value)
+	}
+
+	switch {
+	case msg.TextBody != "" && msg.HTMLBody != "":
+		m.SetBody("text/plain", msg.TextBody)
+		m.AddAlternative("text/html", msg.HTMLBody)
+	case msg.TextBody != "":
+		m.SetBody("text/plain", msg.TextBody)
+	default:
+		m.SetBody("text/html", msg.HTMLBody)
+	}
+	return m
+}
@@ -0,0 +1,145 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		transport string
+		wantType  string
+		wantErr   bool
+	}{
+		{"", "*email.SMTPTransport", false},
+		{"smtp", "*email.SMTPTransport", false},
+		{"file", "*email.FileTransport", false},
+		{"log", "*email.LogTransport", false},
+		{"pigeon", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transport, func(t *testing.T) {
+			transport, err := NewTransport(&EmailConfig{Transport: tt.transport, OutboxDir: t.TempDir()})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTransport error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := fmt.Sprintf("%T", transport); got != tt.wantType {
+					t.Errorf("NewTransport(%q) = %s, want %s", tt.transport, got, tt.wantType)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      Message
+		contains []string
+		excludes []string
+	}{
+		{
+			"纯文本",
+			Message{From: "a@example.com", To: "b@example.com", Subject: "hello", TextBody: "text body"},
+			[]string{"Content-Type: text/plain", "text body"},
+			[]string{"text/html", "multipart/alternative"},
+		},
+		{
+			"HTML",
+			Message{From: "a@example.com", To: "b@example.com", Subject: "hello", HTMLBody: "<p>html</p>"},
+			[]string{"Content-Type: text/html", "<p>html</p>"},
+			[]string{"text/plain", "multipart/alternative"},
+		},
+		{
+			"纯文本和 HTML",
+			Message{From: "a@example.com", To: "b@example.com", Subject: "hello", TextBody: "text body", HTMLBody: "<p>html</p>"},
+			[]string{"multipart/alternative", "text/plain", "text/html"},
+			nil,
+		},
+		{
+			"额外邮件头",
+			Message{From: "a@example.com", To: "b@example.com", Subject: "hello", TextBody: "t",
+				Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"}},
+			[]string{"List-Unsubscribe: <https://example.com/u>"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := buildMessage(&tt.msg).WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo: %v", err)
+			}
+			raw := buf.String()
+			for _, s := range append([]string{"From: a@example.com", "To: b@example.com", "Subject: hello"}, tt.contains...) {
+				if !strings.Contains(raw, s) {
+					t.Errorf("message does not contain %q:\n%s", s, raw)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(raw, s) {
+					t.Errorf("message unexpectedly contains %q:\n%s", s, raw)
+				}
+			}
+			// 同时有纯文本和 HTML 时纯文本在前
+			if tt.msg.TextBody != "" && tt.msg.HTMLBody != "" &&
+				strings.Index(raw, "text/plain") > strings.Index(raw, "text/html") {
+				t.Errorf("text/plain part should come before text/html:\n%s", raw)
+			}
+		})
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	transport, err := NewFileTransport(dir)
+	if err != nil {
+		t.Fatalf("NewFileTransport: %v", err)
+	}
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := transport.Send(&Message{From: "noreply@example.com", To: to, Subject: "hi", TextBody: "body"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("outbox has %d files, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".eml" {
+			t.Errorf("unexpected file %s, temporary files should be renamed to .eml", entry.Name())
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if !strings.Contains(string(data), "Subject: hi") {
+			t.Errorf("%s is not a complete message:\n%s", entry.Name(), data)
+		}
+	}
+}
+
+func TestMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	msg := &Message{To: "a@example.com", Subject: "first"}
+	transport.Send(msg)
+	transport.Send(&Message{To: "b@example.com", Subject: "second"})
+
+	// 保存的是副本，发送后修改原邮件不影响记录
+	msg.Subject = "changed"
+
+	messages := transport.Messages()
+	if len(messages) != 2 || messages[0].Subject != "first" || messages[1].Subject != "second" {
+		t.Errorf("Messages() = %+v, want first and second in order", messages)
+	}
+}
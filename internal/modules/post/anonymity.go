@@ -0,0 +1,142 @@
+package post
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go-tree-hollow/internal/models"
+)
+
+// avatarPathPrefix 化名头像的访问路径，对应 GET /api/v1/avatars/:seed
+const avatarPathPrefix = "/api/v1/avatars/"
+
+// avatarSeedLength 头像种子的十六进制长度
+const avatarSeedLength = 16
+
+var pseudonymAdjectives = []string{
+	"安静的", "迷路的", "晚睡的", "勇敢的", "害羞的", "发呆的", "温柔的", "倔强的",
+	"失眠的", "快乐的", "忧郁的", "好奇的", "慢吞吞的", "认真的", "迷糊的", "孤单的",
+	"明亮的", "沉默的", "自由的", "犹豫的", "热心的", "怕冷的", "爱笑的", "路过的",
+	"想家的", "清醒的", "游荡的", "小小的", "坚强的", "懒洋洋的", "做梦的", "等风的",
+}
+
+var pseudonymNouns = []string{
+	"鲸鱼", "刺猬", "海獭", "松鼠", "企鹅", "狐狸", "猫头鹰", "水母",
+	"仙人掌", "蒲公英", "月亮", "云朵", "路灯", "灯塔", "橘子", "柠檬",
+	"树袋熊", "小鹿", "蜗牛", "萤火虫", "海豚", "兔子", "浣熊", "麻雀",
+	"星星", "雨滴", "风铃", "纸飞机", "向日葵", "棉花糖", "小熊", "候鸟",
+}
+
+// Anonymizer 为匿名帖子生成化名。
+// 化名由密钥对（帖子ID, 用户ID）做 HMAC 得到：同一帖子下同一用户的化名始终相同，
+// 不同帖子之间的化名互不关联，无法据此追踪同一作者。
+type Anonymizer struct {
+	secret []byte
+}
+
+// NewAnonymizer 创建化名生成器。secret 为空时使用随机密钥，重启后化名会改变。
+func NewAnonymizer(secret string) *Anonymizer {
+	if secret == "" {
+		log.Println("Warning: ANONYMOUS_SECRET is not set, using a random key; pseudonyms will change after restart")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("Failed to generate anonymous secret: %v", err)
+		}
+		return &Anonymizer{secret: key}
+	}
+	return &Anonymizer{secret: []byte(secret)}
+}
+
+// Pseudonym 返回用户在指定帖子下的化名
+func (a *Anonymizer) Pseudonym(postID, userID uint, isAuthor bool) *models.Pseudonym {
+	mac := hmac.New(sha256.New, a.secret)
+	fmt.Fprintf(mac, "%d:%d", postID, userID)
+	sum := mac.Sum(nil)
+
+	adjective := pseudonymAdjectives[binary.BigEndian.Uint32(sum[0:4])%uint32(len(pseudonymAdjectives))]
+	noun := pseudonymNouns[binary.BigEndian.Uint32(sum[4:8])%uint32(len(pseudonymNouns))]
+	return &models.Pseudonym{
+		Name:      adjective + noun,
+		AvatarURL: avatarPathPrefix + hex.EncodeToString(sum[8:8+avatarSeedLength/2]) + ".svg",
+		IsAuthor:  isAuthor,
+	}
+}
+
+// presentPost 处理帖子的公开响应：匿名帖子以化名代替作者，实名帖子只保留作者的公开资料
+func (a *Anonymizer) presentPost(post *models.Post) {
+	if post.Anonymous {
+		post.Pseudonym = a.Pseudonym(post.ID, post.UserID, true)
+		post.UserID = 0
+		post.User = nil
+		return
+	}
+	post.User = publicUser(post.User)
+}
+
+// presentComment 处理评论的公开响应：匿名帖子下所有评论者都以该帖子内的化名展示
+func (a *Anonymizer) presentComment(comment *models.Comment, post *models.Post) {
+	if post.Anonymous {
+		comment.Pseudonym = a.Pseudonym(post.ID, comment.UserID, comment.UserID == post.UserID)
+		comment.UserID = 0
+		comment.User = nil
+		return
+	}
+	comment.User = publicUser(comment.User)
+}
+
+// publicUser 只保留可以公开展示的用户资料，不输出邮箱、角色等账号信息
+func publicUser(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+	public := &models.User{
+		Nickname:  user.Nickname,
+		AvatarURL: user.AvatarURL,
+		Bio:       user.Bio,
+	}
+	public.ID = user.ID
+	public.CreatedAt = user.CreatedAt
+	return public
+}
+
+// RenderAvatar 根据种子生成 5x5 左右对称的像素头像（SVG）。
+// 种子无效时返回 false。
+func RenderAvatar(seed string) ([]byte, bool) {
+	seed = strings.TrimSuffix(seed, ".svg")
+	if len(seed) != avatarSeedLength {
+		return nil, false
+	}
+	raw, err := hex.DecodeString(seed)
+	if err != nil {
+		return nil, false
+	}
+
+	hue := int(binary.BigEndian.Uint16(raw[0:2])) % 360
+	bits := binary.BigEndian.Uint32(raw[2:6])
+	color := "hsl(" + strconv.Itoa(hue) + ",55%,55%)"
+
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="-1 -1 7 7" width="120" height="120" shape-rendering="crispEdges">`)
+	b.WriteString(`<rect x="-1" y="-1" width="7" height="7" fill="#f2f2f2"/>`)
+	// 只生成左侧三列，右侧两列镜像
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 5; row++ {
+			if bits&(1<<uint(col*5+row)) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, col, row, color)
+			if col < 2 {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, 4-col, row, color)
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String()), true
+}
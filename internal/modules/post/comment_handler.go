@@ -1,9 +1,14 @@
 package post
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+
+	"go-tree-hollow/pkg/pagination"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CommentHandler struct {
@@ -43,6 +48,10 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	}
 
 	comment, err := h.service.CreateComment(dto)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -52,6 +61,7 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 }
 
 // GetComments handles GET /api/v1/posts/:id/comments
+// Supports page/pageSize, or a keyset cursor via ?cursor= which returns next_cursor instead of total.
 func (h *CommentHandler) GetComments(c *gin.Context) {
 	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -62,7 +72,39 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
 
+	// Cursor mode: pass an empty cursor for the first page, then the returned next_cursor
+	if raw, ok := c.GetQuery("cursor"); ok {
+		cursor, err := pagination.Decode(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		comments, next, err := h.service.GetCommentsByPostCursor(uint(postID), cursor, pageSize)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":        comments,
+			"next_cursor": next,
+		})
+		return
+	}
+
 	comments, total, err := h.service.GetCommentsByPost(uint(postID), page, pageSize)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -76,6 +118,7 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 }
 
 // DeleteComment handles DELETE /api/v1/comments/:id
+// The commenter, the post author and moderators may delete a comment.
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	commentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -83,16 +126,40 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("userID")
-	if !exists {
+	if _, exists := c.Get("userID"); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	if err := h.service.DeleteComment(uint(commentID), userID.(uint)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteComment(uint(commentID), actorFromContext(c)); err != nil {
+		switch {
+		case errors.Is(err, ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
+
+// GetCommentAuthor handles GET /api/v1/admin/comments/:id/author
+// It reveals the real author of a comment in an anonymous thread and is restricted to moderators.
+func (h *CommentHandler) GetCommentAuthor(c *gin.Context) {
+	commentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	author, err := h.service.GetAuthor(uint(commentID))
+	if err != nil || author == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
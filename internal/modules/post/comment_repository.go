@@ -2,12 +2,15 @@ package post
 
 import (
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
 	"gorm.io/gorm"
 )
 
 type CommentRepository interface {
 	Create(comment *models.Comment) error
 	FindByPost(postID uint, page, pageSize int) ([]*models.Comment, int64, error)
+	// FindByPostCursor returns at most limit comments of a post after the cursor, without counting the total
+	FindByPostCursor(postID uint, cursor *pagination.Cursor, limit int) ([]*models.Comment, error)
 	FindByID(id uint) (*models.Comment, error)
 	Delete(id uint) error
 }
@@ -20,8 +23,14 @@ func NewCommentRepository(db *gorm.DB) CommentRepository {
 	return &commentRepository{db: db}
 }
 
+// Create 新增评论，并在同一事务中增加帖子的 comments_count
 func (r *commentRepository) Create(comment *models.Comment) error {
-	return r.db.Create(comment).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return adjustPostCounter(tx, comment.PostID, "comments_count", 1)
+	})
 }
 
 func (r *commentRepository) FindByPost(postID uint, page, pageSize int) ([]*models.Comment, int64, error) {
@@ -46,12 +55,33 @@ func (r *commentRepository) FindByPost(postID uint, page, pageSize int) ([]*mode
 	return comments, total, err
 }
 
+func (r *commentRepository) FindByPostCursor(postID uint, cursor *pagination.Cursor, limit int) ([]*models.Comment, error) {
+	var comments []*models.Comment
+	err := r.db.Where("post_id = ?", postID).
+		Scopes(pagination.Scope(cursor)).
+		Limit(limit).
+		Preload("User").
+		Find(&comments).Error
+	return comments, err
+}
+
 func (r *commentRepository) FindByID(id uint) (*models.Comment, error) {
 	var comment models.Comment
 	err := r.db.Preload("User").First(&comment, id).Error
 	return &comment, err
 }
 
+// Delete 软删除评论，并在同一事务中减少帖子的 comments_count
 func (r *commentRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Comment{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var comment models.Comment
+		if err := tx.Select("id", "post_id").First(&comment, id).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Comment{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return adjustPostCounter(tx, comment.PostID, "comments_count", -1)
+	})
 }
@@ -1,6 +1,14 @@
 package post
 
-import "go-tree-hollow/internal/models"
+import (
+	"errors"
+	"time"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
+
+	"gorm.io/gorm"
+)
 
 type CreateCommentDto struct {
 	UserID  uint   `json:"user_id" binding:"required"`
@@ -11,18 +19,32 @@ type CreateCommentDto struct {
 type CommentService interface {
 	CreateComment(dto *CreateCommentDto) (*models.Comment, error)
 	GetCommentsByPost(postID uint, page, pageSize int) ([]*models.Comment, int64, error)
-	DeleteComment(id, userID uint) error
+	// GetCommentsByPostCursor returns one page of comments after the cursor and the cursor of the
+	// next page (empty when there are no more comments).
+	GetCommentsByPostCursor(postID uint, cursor *pagination.Cursor, limit int) ([]*models.Comment, string, error)
+	// DeleteComment deletes a comment. The commenter, the post author and users with
+	// models.PermModerateContent may delete it; others get ErrForbidden.
+	DeleteComment(id uint, actor Actor) error
+	// GetAuthor returns the real author of a comment, including comments in anonymous threads.
+	GetAuthor(id uint) (*models.User, error)
 }
 
 type commentService struct {
-	repo CommentRepository
+	repo       CommentRepository
+	posts      Repository
+	anonymizer *Anonymizer
 }
 
-func NewCommentService(repo CommentRepository) CommentService {
-	return &commentService{repo: repo}
+func NewCommentService(repo CommentRepository, posts Repository, anonymizer *Anonymizer) CommentService {
+	return &commentService{repo: repo, posts: posts, anonymizer: anonymizer}
 }
 
 func (s *commentService) CreateComment(dto *CreateCommentDto) (*models.Comment, error) {
+	post, err := s.posts.FindByID(dto.PostID)
+	if err != nil {
+		return nil, err
+	}
+
 	comment := &models.Comment{
 		UserID:  dto.UserID,
 		PostID:  dto.PostID,
@@ -33,23 +55,76 @@ func (s *commentService) CreateComment(dto *CreateCommentDto) (*models.Comment,
 		return nil, err
 	}
 
-	return s.repo.FindByID(comment.ID)
+	created, err := s.repo.FindByID(comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.anonymizer.presentComment(created, post)
+	return created, nil
 }
 
 func (s *commentService) GetCommentsByPost(postID uint, page, pageSize int) ([]*models.Comment, int64, error) {
-	return s.repo.FindByPost(postID, page, pageSize)
+	post, err := s.posts.FindByID(postID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	comments, total, err := s.repo.FindByPost(postID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	// In an anonymous thread every commenter keeps the same pseudonym across pages
+	for _, comment := range comments {
+		s.anonymizer.presentComment(comment, post)
+	}
+	return comments, total, nil
+}
+
+func (s *commentService) GetCommentsByPostCursor(postID uint, cursor *pagination.Cursor, limit int) ([]*models.Comment, string, error) {
+	post, err := s.posts.FindByID(postID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to tell whether there is another page
+	comments, err := s.repo.FindByPostCursor(postID, cursor, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, next := pagination.Next(comments, limit, func(c *models.Comment) (time.Time, uint) {
+		return c.CreatedAt, c.ID
+	})
+	for _, comment := range comments {
+		s.anonymizer.presentComment(comment, post)
+	}
+	return comments, next, nil
 }
 
-func (s *commentService) DeleteComment(id, userID uint) error {
+func (s *commentService) DeleteComment(id uint, actor Actor) error {
 	comment, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Only allow user to delete their own comments
-	if comment.UserID != userID {
+	// The post may already be deleted; then only the commenter and moderators may delete the comment
+	post, err := s.posts.FindByID(comment.PostID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		post = nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := authorizeCommentDelete(actor, comment, post); err != nil {
 		return err
 	}
 
 	return s.repo.Delete(id)
 }
+
+func (s *commentService) GetAuthor(id uint) (*models.User, error) {
+	comment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return comment.User, nil
+}
@@ -1,10 +1,14 @@
 package post
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"go-tree-hollow/pkg/pagination"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Handler 处理与帖子相关的 HTTP 请求。它是帖子模块 API 的入口点。
@@ -21,13 +25,14 @@ func NewHandler(service Service) *Handler {
 // CreatePost 处理创建新帖子的 HTTP POST 请求。
 // 它期望一个符合 CreatePostDto 结构体的 JSON 请求体。
 // @Summary 创建新帖子
-// @Description 创建包含文本内容和媒体URL的新帖子。帖子初始状态为草稿。
+// @Description 创建包含文本内容和媒体URL的新帖子。帖子初始状态为草稿。anonymous 为 true 时公开响应中以化名代替作者。
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param post body CreatePostDto true "帖子创建数据"
 // @Success 201 {object} models.Post "成功创建帖子"
 // @Failure 400 {object} gin.H "无效的请求体或缺少必填字段"
+// @Failure 403 {object} gin.H "游客只能在指定话题下发帖"
 // @Failure 500 {object} gin.H "内部服务器错误，例如数据库错误或检测到敏感内容"
 // @Security BearerAuth
 // @Router /posts [post]
@@ -39,10 +44,16 @@ func (h *Handler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	// 帖子作者取自认证中间件设置的当前用户，不接受请求体中指定的用户ID。
+	dto.UserID = c.GetUint("userID")
+	dto.Role = c.GetString("role")
+
 	// 调用服务层创建帖子。
-	// 帖子的 UserID 通常应从已认证用户的上下文（例如 JWT claims）中获取。
-	// 此处假设认证中间件已在 DTO 或上下文中设置了 UserID。
 	post, err := h.service.CreatePost(&dto)
+	if errors.Is(err, ErrTagNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -91,6 +102,53 @@ func (h *Handler) GetPost(c *gin.Context) {
 	c.JSON(http.StatusOK, post)
 }
 
+// GetPostAuthor 处理查询帖子真实作者的 HTTP GET 请求，匿名帖子同样返回真实作者。
+// @Summary 查询帖子作者
+// @Description 返回帖子的真实作者，仅限拥有内容审核权限的用户调用。
+// @Tags admin
+// @Produce json
+// @Param id path int true "帖子ID"
+// @Success 200 {object} models.User "帖子作者"
+// @Failure 400 {object} gin.H "无效的帖子ID格式"
+// @Failure 403 {object} gin.H "权限不足"
+// @Failure 404 {object} gin.H "未找到帖子"
+// @Security BearerAuth
+// @Router /admin/posts/{id}/author [get]
+func (h *Handler) GetPostAuthor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID格式"})
+		return
+	}
+
+	author, err := h.service.GetAuthor(uint(id))
+	if err != nil || author == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到帖子"})
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+// GetAvatar 返回匿名化名对应的头像图片（SVG），头像由种子确定，可长期缓存。
+// @Summary 获取化名头像
+// @Tags posts
+// @Produce image/svg+xml
+// @Param seed path string true "头像种子"
+// @Success 200 "SVG 图片"
+// @Failure 404 {object} gin.H "无效的头像"
+// @Router /avatars/{seed} [get]
+func (h *Handler) GetAvatar(c *gin.Context) {
+	svg, ok := RenderAvatar(c.Param("seed"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "无效的头像"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, "image/svg+xml", svg)
+}
+
 // UpdatePost 处理更新现有帖子的 HTTP PUT 请求。
 // 它期望帖子ID作为路径参数，并期望一个符合 UpdatePostDto 结构体的 JSON 请求体。
 // @Summary 更新现有帖子
@@ -102,6 +160,7 @@ func (h *Handler) GetPost(c *gin.Context) {
 // @Param post body UpdatePostDto true "帖子更新数据"
 // @Success 200 {object} models.Post "成功更新帖子"
 // @Failure 400 {object} gin.H "无效的请求体、帖子ID格式或敏感内容"
+// @Failure 403 {object} gin.H "不是帖子作者，或游客只能在指定话题下发帖"
 // @Failure 404 {object} gin.H "未找到帖子"
 // @Failure 500 {object} gin.H "内部服务器错误"
 // @Security BearerAuth
@@ -121,8 +180,16 @@ func (h *Handler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	// 调用服务层更新帖子。
-	post, err := h.service.UpdatePost(uint(id), &dto)
+	// 调用服务层更新帖子，只有作者本人可以编辑。
+	post, err := h.service.UpdatePost(uint(id), actorFromContext(c), &dto)
+	if errors.Is(err, ErrForbidden) || errors.Is(err, ErrTagNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到帖子"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -135,11 +202,13 @@ func (h *Handler) UpdatePost(c *gin.Context) {
 // DeletePost 处理根据ID删除帖子的 HTTP DELETE 请求。
 // 它期望帖子ID作为路径参数。此操作执行软删除。
 // @Summary 删除帖子
-// @Description 软删除由其ID标识的帖子。帖子不会被永久删除，而是被标记为已删除。
+// @Description 软删除由其ID标识的帖子。帖子不会被永久删除，而是被标记为已删除。作者本人和版主可以删除帖子。
 // @Tags posts
 // @Param id path int true "帖子ID"
 // @Success 204 "成功删除帖子 (无内容)"
 // @Failure 400 {object} gin.H "无效的帖子ID格式"
+// @Failure 403 {object} gin.H "不是帖子作者且没有内容审核权限"
+// @Failure 404 {object} gin.H "未找到帖子"
 // @Failure 500 {object} gin.H "内部服务器错误"
 // @Security BearerAuth
 // @Router /posts/{id} [delete]
@@ -152,8 +221,15 @@ func (h *Handler) DeletePost(c *gin.Context) {
 	}
 
 	// 调用服务层删除帖子。
-	if err := h.service.DeletePost(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeletePost(uint(id), actorFromContext(c)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -225,15 +301,17 @@ func (h *Handler) ListPosts(c *gin.Context) {
 
 // GetAllPosts 处理列出所有用户帖子的 HTTP GET 请求。
 // 它接受可选的 'page'、'pageSize' 和 'tag_id' 查询参数用于分页和过滤。
+// 传入 'cursor' 参数时改用游标分页，响应中不含 total 和 page，而是返回 next_cursor。
 // @Summary 列出所有帖子
 // @Description 检索所有用户的分页帖子列表，可选按标签过滤。
 // @Tags posts
 // @Produce json
 // @Param page query int false "页码 (默认为1)"
 // @Param pageSize query int false "每页项目数 (默认为10)"
+// @Param cursor query string false "分页游标 (第一页传空值，之后传上一页的 next_cursor)"
 // @Param tag_id query int false "标签ID (可选，用于按标签过滤帖子)"
-// @Success 200 {object} gin.H{data=[]models.Post,total=int64,page=int} "成功检索到帖子列表"
-// @Failure 400 {object} gin.H "无效的标签ID格式或查询参数"
+// @Success 200 {object} gin.H{data=[]models.Post,total=int64,page=int,next_cursor=string} "成功检索到帖子列表"
+// @Failure 400 {object} gin.H "无效的标签ID格式、游标或查询参数"
 // @Failure 500 {object} gin.H "内部服务器错误"
 // @Security BearerAuth
 // @Router /posts [get]
@@ -261,6 +339,31 @@ func (h *Handler) GetAllPosts(c *gin.Context) {
 		currentUserIDPtr = &uid
 	}
 
+	// 游标模式：第一页传空的 cursor，之后传上一页返回的 next_cursor，不返回总数
+	if raw, ok := c.GetQuery("cursor"); ok {
+		cursor, err := pagination.Decode(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 10
+		}
+
+		posts, next, err := h.service.GetAllPostsByCursor(cursor, pageSize, tagIDPtr, currentUserIDPtr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// next_cursor 为空表示没有更多帖子
+		c.JSON(http.StatusOK, gin.H{
+			"data":        posts,
+			"next_cursor": next,
+		})
+		return
+	}
+
 	// 调用服务层获取所有用户的分页帖子列表，可选按 tag 过滤。
 	posts, total, err := h.service.GetAllPosts(page, pageSize, tagIDPtr, currentUserIDPtr)
 	if err != nil {
@@ -4,14 +4,23 @@ import (
 	"go-tree-hollow/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Repository defines like data access operations
+// LikeRepository 点赞的数据访问
 type LikeRepository interface {
+	// Create 新增点赞，并在同一事务中增加帖子的 likes_count；已点赞时不做改动
 	Create(like *models.Like) error
+	// Delete 取消点赞，并在同一事务中减少帖子的 likes_count
 	Delete(userID, postID uint) error
+	// Toggle 在一个事务中点赞，已点赞时改为取消，返回操作后是否处于点赞状态。
+	// 依赖 likes(user_id, post_id) 的唯一索引，并发请求不会重复插入点赞，也不会重复修改 likes_count
+	Toggle(userID, postID uint) (bool, error)
 	FindByUserAndPost(userID, postID uint) (*models.Like, error)
+	// CountByPost 返回帖子上存储的 likes_count
 	CountByPost(postID uint) (int64, error)
+	// FindLikedPostIDs 用一次查询返回给定帖子中用户已点赞的帖子
+	FindLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error)
 }
 
 type likeRepository struct {
@@ -23,11 +32,45 @@ func NewLikeRepository(db *gorm.DB) LikeRepository {
 }
 
 func (r *likeRepository) Create(like *models.Like) error {
-	return r.db.Create(like).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(like)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return adjustPostCounter(tx, like.PostID, "likes_count", 1)
+	})
 }
 
 func (r *likeRepository) Delete(userID, postID uint) error {
-	return r.db.Unscoped().Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Like{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Like{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return adjustPostCounter(tx, postID, "likes_count", -result.RowsAffected)
+	})
+}
+
+func (r *likeRepository) Toggle(userID, postID uint) (bool, error) {
+	liked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Like{UserID: userID, PostID: postID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			liked = true
+			return adjustPostCounter(tx, postID, "likes_count", 1)
+		}
+
+		// 已点赞则取消；并发的请求先取消时 RowsAffected 为 0
+		result = tx.Unscoped().Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Like{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return adjustPostCounter(tx, postID, "likes_count", -result.RowsAffected)
+	})
+	return liked, err
 }
 
 func (r *likeRepository) FindByUserAndPost(userID, postID uint) (*models.Like, error) {
@@ -37,7 +80,33 @@ func (r *likeRepository) FindByUserAndPost(userID, postID uint) (*models.Like, e
 }
 
 func (r *likeRepository) CountByPost(postID uint) (int64, error) {
-	var count int64
-	err := r.db.Model(&models.Like{}).Where("post_id = ?", postID).Count(&count).Error
-	return count, err
+	var post models.Post
+	err := r.db.Select("likes_count").First(&post, postID).Error
+	return post.LikesCount, err
+}
+
+func (r *likeRepository) FindLikedPostIDs(userID uint, postIDs []uint) (map[uint]bool, error) {
+	liked := make(map[uint]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return liked, nil
+	}
+
+	var ids []uint
+	err := r.db.Model(&models.Like{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
+
+// adjustPostCounter 给帖子的计数列加上 delta，必须在修改对应记录的同一事务中调用，保证计数不会偏差
+func adjustPostCounter(tx *gorm.DB, postID uint, column string, delta int64) error {
+	return tx.Model(&models.Post{}).Unscoped().
+		Where("id = ?", postID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
 }
@@ -1,7 +1,6 @@
 package post
 
 import (
-	"go-tree-hollow/internal/models"
 	"gorm.io/gorm"
 )
 
@@ -19,29 +18,9 @@ func NewLikeService(repo LikeRepository) LikeService {
 	return &likeService{repo: repo}
 }
 
+// ToggleLike likes or unlikes the post atomically, see LikeRepository.Toggle
 func (s *likeService) ToggleLike(userID, postID uint) (bool, error) {
-	// Check if already liked
-	_, err := s.repo.FindByUserAndPost(userID, postID)
-	
-	if err == gorm.ErrRecordNotFound {
-		// Not liked yet, create like
-		like := &models.Like{
-			UserID: userID,
-			PostID: postID,
-		}
-		if err := s.repo.Create(like); err != nil {
-			return false, err
-		}
-		return true, nil
-	} else if err != nil {
-		return false, err
-	}
-	
-	// Already liked, remove like
-	if err := s.repo.Delete(userID, postID); err != nil {
-		return false, err
-	}
-	return false, nil
+	return s.repo.Toggle(userID, postID)
 }
 
 func (s *likeService) GetLikeCount(postID uint) (int64, error) {
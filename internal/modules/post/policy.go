@@ -0,0 +1,62 @@
+package post
+
+import (
+	"errors"
+
+	"go-tree-hollow/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrForbidden 表示当前用户无权修改或删除该内容。
+var ErrForbidden = errors.New("无权操作该内容")
+
+// Actor 发起修改或删除操作的用户。
+type Actor struct {
+	UserID uint
+	Role   string
+}
+
+// actorFromContext 从认证中间件设置的上下文中获取当前用户。
+func actorFromContext(c *gin.Context) Actor {
+	return Actor{UserID: c.GetUint("userID"), Role: c.GetString("role")}
+}
+
+// canModerate 判断用户是否拥有处理他人内容的权限（版主及以上）。
+func (a Actor) canModerate() bool {
+	return models.HasPermission(a.Role, models.PermModerateContent)
+}
+
+// authorizePostUpdate 只有作者本人可以编辑帖子，版主也不能修改他人的内容。
+func authorizePostUpdate(actor Actor, post *models.Post) error {
+	if actor.UserID != 0 && actor.UserID == post.UserID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// authorizePostDelete 作者本人或拥有内容审核权限的用户可以删除帖子。
+func authorizePostDelete(actor Actor, post *models.Post) error {
+	if actor.UserID != 0 && actor.UserID == post.UserID {
+		return nil
+	}
+	if actor.canModerate() {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// authorizeCommentDelete 评论者本人、帖子作者或拥有内容审核权限的用户可以删除评论。
+// post 为空表示帖子已被删除，此时只按评论者本人和审核权限判断。
+func authorizeCommentDelete(actor Actor, comment *models.Comment, post *models.Post) error {
+	if actor.UserID != 0 && actor.UserID == comment.UserID {
+		return nil
+	}
+	if post != nil && actor.UserID != 0 && actor.UserID == post.UserID {
+		return nil
+	}
+	if actor.canModerate() {
+		return nil
+	}
+	return ErrForbidden
+}
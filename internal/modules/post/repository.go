@@ -2,8 +2,10 @@ package post
 
 import (
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository 定义了帖子数据操作的接口，抽象了数据库交互。
@@ -16,10 +18,12 @@ type Repository interface {
 	Update(post *models.Post) error
 	// Delete 通过设置 'deleted_at' 时间戳将帖子标记为删除（软删除）。
 	Delete(id uint) error
-	// FindAllByUserID 检索特定用户的分页帖子列表，可选按 tag 过滤。
-	FindAllByUserID(userID uint, page, pageSize int, tagID *uint) ([]*models.Post, int64, error)
+	// FindAllByUserID 检索特定用户的分页帖子列表，可选按 tag 过滤，includeAnonymous 为 false 时排除匿名帖子。
+	FindAllByUserID(userID uint, page, pageSize int, tagID *uint, includeAnonymous bool) ([]*models.Post, int64, error)
 	// FindAll 检索所有用户的分页帖子列表，可选按 tag 过滤。
 	FindAll(page, pageSize int, tagID *uint) ([]*models.Post, int64, error)
+	// FindAllByCursor 按游标检索所有用户的帖子，最多返回 limit 条，可选按 tag 过滤，不统计总数。
+	FindAllByCursor(cursor *pagination.Cursor, limit int, tagID *uint) ([]*models.Post, error)
 }
 
 // repository 使用 GORM 实现了 Repository 接口。
@@ -51,8 +55,10 @@ func (r *repository) FindByID(id uint) (*models.Post, error) {
 // Update 保存数据库中现有帖子记录的更改。
 // 它接收一个带有更新字段的 models.Post 结构体指针。
 // GORM 将更新所有非零值或明确标记的字段。
+// 点赞数和评论数由点赞、评论操作维护，这里不写入，避免用读取时的旧值覆盖并发的计数更新；
+// 预加载的 User 和 Tag 也不写回，否则修改 TagID 时会被旧的 Tag 覆盖。
 func (r *repository) Update(post *models.Post) error {
-	return r.db.Save(post).Error
+	return r.db.Omit(clause.Associations, "likes_count", "comments_count").Save(post).Error
 }
 
 // Delete 通过设置 'deleted_at' 时间戳将帖子标记为删除（软删除）。
@@ -64,12 +70,16 @@ func (r *repository) Delete(id uint) error {
 
 // FindAllByUserID 检索特定用户的分页帖子列表，可选按 tag 过滤。
 // 它接收用户ID、页码、页面大小和可选的 tagID，返回帖子切片以及该用户帖子的总数和遇到的任何错误。
-func (r *repository) FindAllByUserID(userID uint, page, pageSize int, tagID *uint) ([]*models.Post, int64, error) {
+// 匿名帖子只在作者本人查看时返回，否则他人可通过用户主页将匿名帖子与作者对应起来。
+func (r *repository) FindAllByUserID(userID uint, page, pageSize int, tagID *uint, includeAnonymous bool) ([]*models.Post, int64, error) {
 	var posts []*models.Post
 	var total int64
 
 	// 构建按用户ID过滤帖子的基本查询。
 	query := r.db.Model(&models.Post{}).Where("user_id = ?", userID)
+	if !includeAnonymous {
+		query = query.Where("anonymous = ?", false)
+	}
 
 	// 如果提供了 tagID，添加 tag 过滤
 	if tagID != nil {
@@ -115,3 +125,17 @@ func (r *repository) FindAll(page, pageSize int, tagID *uint) ([]*models.Post, i
 
 	return posts, total, err
 }
+
+// FindAllByCursor 按游标检索所有用户的帖子，可选按 tag 过滤。
+// 与 FindAll 不同，它不执行 COUNT 和 OFFSET，翻页开销不随页数增长。
+func (r *repository) FindAllByCursor(cursor *pagination.Cursor, limit int, tagID *uint) ([]*models.Post, error) {
+	var posts []*models.Post
+
+	query := r.db.Model(&models.Post{}).Scopes(pagination.Scope(cursor))
+	if tagID != nil {
+		query = query.Where("tag_id = ?", *tagID)
+	}
+
+	err := query.Preload("User").Preload("Tag").Limit(limit).Find(&posts).Error
+	return posts, err
+}
@@ -4,12 +4,15 @@ import "github.com/gin-gonic/gin"
 
 // Routes 为帖子模块在给定的 Gin 路由组中设置 API 路由。
 // 这里定义的所有路由都受提供的认证中间件保护。
-func Routes(r *gin.RouterGroup, handler *Handler, likeHandler *LikeHandler, commentHandler *CommentHandler, authMiddleware gin.HandlerFunc, optionalAuthMiddleware gin.HandlerFunc) {
+// moderatorMiddleware 用于限制查询匿名内容真实作者的管理接口。
+func Routes(r *gin.RouterGroup, handler *Handler, likeHandler *LikeHandler, commentHandler *CommentHandler, searchHandler *SearchHandler, trendingHandler *TrendingHandler, authMiddleware gin.HandlerFunc, optionalAuthMiddleware gin.HandlerFunc, moderatorMiddleware gin.HandlerFunc) {
 	// 公开路由组（不需要认证）
 	publicPosts := r.Group("/posts")
 	{
-		publicPosts.GET("", handler.GetAllPosts)                                               // GET /api/v1/posts - 获取所有帖子
-		publicPosts.GET("/:id", handler.GetPost)                                               // GET /api/v1/posts/:id - 获取单个帖子
+		publicPosts.GET("", optionalAuthMiddleware, handler.GetAllPosts)                       // GET /api/v1/posts - 获取所有帖子
+		publicPosts.GET("/search", optionalAuthMiddleware, searchHandler.SearchPosts)          // GET /api/v1/posts/search - 搜索帖子
+		publicPosts.GET("/trending", optionalAuthMiddleware, trendingHandler.GetTrending)      // GET /api/v1/posts/trending - 热门帖子
+		publicPosts.GET("/:id", optionalAuthMiddleware, handler.GetPost)                       // GET /api/v1/posts/:id - 获取单个帖子
 		publicPosts.GET("/:id/like/status", optionalAuthMiddleware, likeHandler.GetLikeStatus) // GET /api/v1/posts/:id/like/status - 获取点赞状态
 		publicPosts.GET("/:id/comments", commentHandler.GetComments)                           // GET /api/v1/posts/:id/comments - 获取评论列表
 	}
@@ -29,6 +32,9 @@ func Routes(r *gin.RouterGroup, handler *Handler, likeHandler *LikeHandler, comm
 		authPosts.POST("/:id/comments", commentHandler.CreateComment) // POST /api/v1/posts/:id/comments - 创建评论
 	}
 
+	// 匿名化名头像（公开）
+	r.GET("/avatars/:seed", handler.GetAvatar) // GET /api/v1/avatars/:seed - 获取化名头像
+
 	// 删除评论（需要认证）
 	r.DELETE("/comments/:id", authMiddleware, commentHandler.DeleteComment)
 
@@ -38,4 +44,12 @@ func Routes(r *gin.RouterGroup, handler *Handler, likeHandler *LikeHandler, comm
 	{
 		users.GET("/:userID/posts", handler.ListPosts) // GET /api/v1/users/:userID/posts - 列出用户帖子
 	}
+
+	// 查询匿名内容的真实作者（需要内容审核权限）
+	admin := r.Group("/admin")
+	admin.Use(authMiddleware, moderatorMiddleware)
+	{
+		admin.GET("/posts/:id/author", handler.GetPostAuthor)              // GET /api/v1/admin/posts/:id/author - 查询帖子作者
+		admin.GET("/comments/:id/author", commentHandler.GetCommentAuthor) // GET /api/v1/admin/comments/:id/author - 查询评论作者
+	}
 }
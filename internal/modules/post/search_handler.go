@@ -0,0 +1,101 @@
+package post
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchDateLayout from / to 查询参数的日期格式
+const searchDateLayout = "2006-01-02"
+
+// SearchHandler 处理帖子搜索请求
+type SearchHandler struct {
+	service SearchService
+}
+
+func NewSearchHandler(service SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// SearchPosts 全文搜索已发布的帖子，按相关度排序
+// @Summary 搜索帖子
+// @Description 按关键词全文搜索已发布的帖子，多个关键词以空格分隔且需全部命中，支持按话题和发布日期过滤。
+// @Tags posts
+// @Produce json
+// @Param q query string true "关键词，如：考试 焦虑"
+// @Param tag_id query int false "标签ID"
+// @Param from query string false "发布日期下限 (YYYY-MM-DD，含当天)"
+// @Param to query string false "发布日期上限 (YYYY-MM-DD，含当天)"
+// @Param page query int false "页码 (默认为1)"
+// @Param pageSize query int false "每页项目数 (默认为10，最大50)"
+// @Success 200 {object} gin.H{data=[]SearchResult,total=int64,page=int} "搜索结果，highlight 为带 <mark> 标记的摘要"
+// @Failure 400 {object} gin.H "关键词为空或参数格式无效"
+// @Failure 500 {object} gin.H "内部服务器错误"
+// @Router /posts/search [get]
+func (h *SearchHandler) SearchPosts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 10
+	}
+
+	dto := &SearchPostsDto{
+		Query:    c.Query("q"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if tagIDStr := c.Query("tag_id"); tagIDStr != "" {
+		tagID, err := strconv.ParseUint(tagIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID格式"})
+			return
+		}
+		tagIDUint := uint(tagID)
+		dto.TagID = &tagIDUint
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation(searchDateLayout, from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		dto.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation(searchDateLayout, to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		// 包含截止日期当天
+		t = t.AddDate(0, 0, 1)
+		dto.To = &t
+	}
+
+	if uid, exists := c.Get("userID"); exists {
+		userID := uid.(uint)
+		dto.CurrentUserID = &userID
+	}
+
+	results, total, err := h.service.SearchPosts(dto)
+	if errors.Is(err, ErrEmptySearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  results,
+		"total": total,
+		"page":  dto.Page,
+	})
+}
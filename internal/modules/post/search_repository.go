@@ -0,0 +1,102 @@
+package post
+
+import (
+	"strings"
+	"time"
+
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchQuery 帖子全文检索条件
+type SearchQuery struct {
+	Tokens   []string // segment.Query 切分后的词元，帖子需包含全部词元
+	TagID    *uint
+	From     *time.Time // 发布时间下限（含）
+	To       *time.Time // 发布时间上限（不含）
+	Page     int
+	PageSize int
+}
+
+// SearchRepository 帖子全文检索。PostgreSQL 使用 posts.search_vector 上的 GIN 索引，
+// SQLite 使用 FTS5 虚拟表 posts_fts，两者都索引 posts.search_tokens。
+type SearchRepository interface {
+	// Search 按相关度排序返回一页已发布的帖子，以及匹配的总数
+	Search(q *SearchQuery) ([]*models.Post, int64, error)
+}
+
+// NewSearchRepository 根据数据库类型创建检索实现
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	if db.Dialector.Name() == "postgres" {
+		return &postgresSearchRepository{db: db}
+	}
+	return &sqliteSearchRepository{db: db}
+}
+
+type postgresSearchRepository struct {
+	db *gorm.DB
+}
+
+func (r *postgresSearchRepository) Search(q *SearchQuery) ([]*models.Post, int64, error) {
+	quoted := make([]string, len(q.Tokens))
+	for i, token := range q.Tokens {
+		quoted[i] = "'" + strings.ReplaceAll(token, "'", "''") + "'"
+	}
+	tsquery := strings.Join(quoted, " & ")
+
+	query := searchFilters(r.db.Model(&models.Post{}), q).
+		Where("posts.search_vector @@ to_tsquery('simple', ?)", tsquery)
+	order := clause.Expr{SQL: "ts_rank(posts.search_vector, to_tsquery('simple', ?)) DESC, posts.created_at DESC", Vars: []interface{}{tsquery}}
+	return searchPage(query, order, q)
+}
+
+type sqliteSearchRepository struct {
+	db *gorm.DB
+}
+
+func (r *sqliteSearchRepository) Search(q *SearchQuery) ([]*models.Post, int64, error) {
+	quoted := make([]string, len(q.Tokens))
+	for i, token := range q.Tokens {
+		quoted[i] = `"` + strings.ReplaceAll(token, `"`, `""`) + `"`
+	}
+
+	query := searchFilters(r.db.Model(&models.Post{}), q).
+		Joins("JOIN posts_fts ON posts_fts.rowid = posts.id").
+		Where("posts_fts MATCH ?", strings.Join(quoted, " "))
+	// bm25 越小越相关
+	order := clause.Expr{SQL: "bm25(posts_fts), posts.created_at DESC"}
+	return searchPage(query, order, q)
+}
+
+// searchFilters 添加两种实现共用的过滤条件：只搜索已发布的帖子，已删除的帖子由软删除条件排除
+func searchFilters(query *gorm.DB, q *SearchQuery) *gorm.DB {
+	query = query.Where("posts.status = ?", models.PostStatusPublished)
+	if q.TagID != nil {
+		query = query.Where("posts.tag_id = ?", *q.TagID)
+	}
+	if q.From != nil {
+		query = query.Where("posts.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("posts.created_at < ?", *q.To)
+	}
+	return query
+}
+
+// searchPage 统计总数并按 order 取出一页，order 为相关度排序，相关度相同时新帖在前
+func searchPage(query *gorm.DB, order clause.Expr, q *SearchQuery) ([]*models.Post, int64, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []*models.Post
+	err := query.Select("posts.*").
+		Clauses(clause.OrderBy{Expression: order}).
+		Preload("User").Preload("Tag").
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).
+		Find(&posts).Error
+	return posts, total, err
+}
@@ -0,0 +1,144 @@
+package post
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/segment"
+)
+
+// ErrEmptySearchQuery 表示搜索关键词为空或只包含标点
+var ErrEmptySearchQuery = errors.New("搜索关键词不能为空")
+
+const (
+	// maxSearchTokens 查询最多使用的词元数量，超出部分忽略
+	maxSearchTokens = 32
+	// snippetLength 摘要的最大字数，snippetContext 为首个命中位置之前保留的字数
+	snippetLength  = 80
+	snippetContext = 20
+)
+
+// SearchPostsDto 帖子搜索参数
+type SearchPostsDto struct {
+	Query         string
+	TagID         *uint
+	From          *time.Time // 发布时间下限（含）
+	To            *time.Time // 发布时间上限（不含）
+	Page          int
+	PageSize      int
+	CurrentUserID *uint
+}
+
+// SearchResult 一条搜索结果，Highlight 为正文摘要，命中的关键词以 <mark> 标记，其余内容已做 HTML 转义
+type SearchResult struct {
+	*models.Post
+	Highlight string `json:"highlight"`
+}
+
+// SearchService 帖子全文检索
+type SearchService interface {
+	// SearchPosts 按相关度搜索已发布的帖子，返回一页结果和匹配总数
+	SearchPosts(dto *SearchPostsDto) ([]*SearchResult, int64, error)
+}
+
+type searchService struct {
+	repo       SearchRepository
+	likeRepo   LikeRepository
+	anonymizer *Anonymizer
+}
+
+func NewSearchService(repo SearchRepository, likeRepo LikeRepository, anonymizer *Anonymizer) SearchService {
+	return &searchService{repo: repo, likeRepo: likeRepo, anonymizer: anonymizer}
+}
+
+func (s *searchService) SearchPosts(dto *SearchPostsDto) ([]*SearchResult, int64, error) {
+	tokens := segment.Query(dto.Query)
+	if len(tokens) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	if len(tokens) > maxSearchTokens {
+		tokens = tokens[:maxSearchTokens]
+	}
+	if dto.Page < 1 {
+		dto.Page = 1
+	}
+	if dto.PageSize < 1 {
+		dto.PageSize = 10
+	}
+
+	posts, total, err := s.repo.Search(&SearchQuery{
+		Tokens:   tokens,
+		TagID:    dto.TagID,
+		From:     dto.From,
+		To:       dto.To,
+		Page:     dto.Page,
+		PageSize: dto.PageSize,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 匿名帖子与信息流一样以化名展示，搜索结果不会暴露作者
+	fillLikeInfo(s.likeRepo, posts, dto.CurrentUserID)
+	results := make([]*SearchResult, len(posts))
+	for i, post := range posts {
+		s.anonymizer.presentPost(post)
+		results[i] = &SearchResult{Post: post, Highlight: highlight(post.TextContent, tokens)}
+	}
+	return results, total, nil
+}
+
+// highlight 截取正文中首个命中位置附近的摘要，并用 <mark> 标记所有命中的词元。
+// 相邻的词元（如“考试”“试焦”“焦虑”）合并为一个标记。
+func highlight(text string, tokens []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, token := range tokens {
+		t := []rune(token)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != token {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start := 0
+	if first > snippetContext {
+		start = first - snippetContext
+	}
+	end := min(len(runes), start+snippetLength)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
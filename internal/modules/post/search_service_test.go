@@ -0,0 +1,40 @@
+package post
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("a", 30) + "考试" + strings.Repeat("b", 100)
+
+	tests := []struct {
+		name   string
+		text   string
+		tokens []string
+		want   string
+	}{
+		{"单个命中", "今天考试很焦虑", []string{"考试"}, "今天<mark>考试</mark>很焦虑"},
+		{"相邻词元合并", "考试焦虑", []string{"考试", "试焦", "焦虑"}, "<mark>考试焦虑</mark>"},
+		{"不相邻的词元分开标记", "考试 焦虑", []string{"考试", "焦虑"}, "<mark>考试</mark> <mark>焦虑</mark>"},
+		{"重复出现都标记", "焦虑，很焦虑", []string{"焦虑"}, "<mark>焦虑</mark>，很<mark>焦虑</mark>"},
+		{"忽略大小写并保留原文", "Learning Go", []string{"go"}, "Learning <mark>Go</mark>"},
+		{"转义 HTML", "<b>考试</b>", []string{"考试"}, "&lt;b&gt;<mark>考试</mark>&lt;/b&gt;"},
+		{"没有命中", "abc", []string{"考试"}, "abc"},
+		{"空文本", "", []string{"考试"}, ""},
+		{
+			"命中位置靠后时截取前后文",
+			long,
+			[]string{"考试"},
+			"…" + strings.Repeat("a", snippetContext) + "<mark>考试</mark>" + strings.Repeat("b", snippetLength-snippetContext-2) + "…",
+		},
+		{"没有命中时截取开头", strings.Repeat("a", 100), []string{"考试"}, strings.Repeat("a", snippetLength) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.tokens); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.tokens, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/pkg/pagination"
 	"log"
+	"time"
 
 	"github.com/importcjj/sensitive"
 	"gorm.io/datatypes"
@@ -12,10 +14,14 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// ErrTagNotAllowed 表示当前用户不能在该话题下发帖（游客只能在指定话题下发帖）。
+var ErrTagNotAllowed = errors.New("游客只能在指定话题下发帖")
+
 // CreatePostDto 定义了创建新帖子的数据结构。
 // 它用于输入验证以及从处理程序到服务层的数据传输。
 type CreatePostDto struct {
-	UserID      uint     `json:"user_id" binding:"required"`
+	UserID      uint     `json:"-"` // 由处理程序从认证上下文中设置
+	Role        string   `json:"-"` // 发帖用户的角色，由处理程序从认证上下文中设置
 	TextContent string   `json:"text_content"`
 	Images      []string `json:"images"`
 	Video       string   `json:"video"`
@@ -23,6 +29,7 @@ type CreatePostDto struct {
 	Cover       string   `json:"cover"`
 	Status      string   `json:"status"`
 	TagID       *uint    `json:"tag_id"` // Single tag ID for one-to-one relationship
+	Anonymous   bool     `json:"anonymous"`
 }
 
 // UpdatePostDto 定义了更新现有帖子的数据结构。
@@ -45,36 +52,64 @@ type Service interface {
 	// GetPost retrieves a single post by its ID from the database.
 	GetPost(id uint, currentUserID *uint) (*models.Post, error)
 	// UpdatePost handles updates to an existing post, applying sensitive word filtering and partial updates.
-	UpdatePost(id uint, dto *UpdatePostDto) (*models.Post, error)
+	// Only the author may update a post; others get ErrForbidden.
+	UpdatePost(id uint, actor Actor, dto *UpdatePostDto) (*models.Post, error)
 	// DeletePost handles the soft deletion of a post by its ID.
-	DeletePost(id uint) error
+	// The author and users with models.PermModerateContent may delete a post; others get ErrForbidden.
+	DeletePost(id uint, actor Actor) error
+	// GetAuthor returns the real author of a post, including anonymous ones. Only moderators may call it.
+	GetAuthor(id uint) (*models.User, error)
 	// ListPosts retrieves a paginated list of posts associated with a specific user ID, optionally filtered by tag.
+	// Anonymous posts are only included when the current user is the author.
 	ListPosts(userID uint, page, pageSize int, tagID *uint, currentUserID *uint) ([]*models.Post, int64, error)
 	// GetAllPosts retrieves a paginated list of all posts from all users, optionally filtered by tag.
 	GetAllPosts(page, pageSize int, tagID *uint, currentUserID *uint) ([]*models.Post, int64, error)
+	// GetAllPostsByCursor retrieves one page of all posts after the cursor, optionally filtered by tag,
+	// and returns the cursor of the next page (empty when there are no more posts).
+	GetAllPostsByCursor(cursor *pagination.Cursor, limit int, tagID *uint, currentUserID *uint) ([]*models.Post, string, error)
 }
 
 // service implements the Service interface, encapsulating business rules and interacting with the repository layer.
 type service struct {
-	db       *gorm.DB
-	repo     Repository
-	likeRepo LikeRepository
-	filter   *sensitive.Filter
+	db          *gorm.DB
+	repo        Repository
+	likeRepo    LikeRepository
+	anonymizer  *Anonymizer
+	filter      *sensitive.Filter
+	guestTagIDs map[uint]bool // 游客可以发帖的话题
 }
 
 // NewService creates a new post service instance.
-func NewService(db *gorm.DB, repo Repository, likeRepo LikeRepository) Service {
+// guestTagIDs lists the tags that users without models.PermPostAnyTag (guests) may post in.
+func NewService(db *gorm.DB, repo Repository, likeRepo LikeRepository, anonymizer *Anonymizer, guestTagIDs []uint) Service {
 	filter := sensitive.New()
 	err := filter.LoadWordDict("dict.txt")
 	if err != nil {
 		log.Printf("Warning: Failed to load sensitive word dictionary from 'dict.txt': %v", err)
 	}
+	allowed := make(map[uint]bool, len(guestTagIDs))
+	for _, id := range guestTagIDs {
+		allowed[id] = true
+	}
 	return &service{
-		db:       db,
-		repo:     repo,
-		likeRepo: likeRepo,
-		filter:   filter,
+		db:          db,
+		repo:        repo,
+		likeRepo:    likeRepo,
+		anonymizer:  anonymizer,
+		filter:      filter,
+		guestTagIDs: allowed,
+	}
+}
+
+// checkTagAllowed verifies that a user with the given role may post in the tag.
+func (s *service) checkTagAllowed(role string, tagID *uint) error {
+	if models.HasPermission(role, models.PermPostAnyTag) {
+		return nil
+	}
+	if tagID == nil || !s.guestTagIDs[*tagID] {
+		return ErrTagNotAllowed
 	}
+	return nil
 }
 
 // handleTagsInTx manages the finding and creation of tags within a database transaction.
@@ -97,6 +132,10 @@ func (s *service) handleTagsInTx(tx *gorm.DB, tagNames []string) ([]*models.Tag,
 
 // CreatePost handles the logic for creating a new post.
 func (s *service) CreatePost(dto *CreatePostDto) (*models.Post, error) {
+	if err := s.checkTagAllowed(dto.Role, dto.TagID); err != nil {
+		return nil, err
+	}
+
 	// Determine post type and media URLs
 	var postType string
 	var mediaUrls []string
@@ -130,7 +169,8 @@ func (s *service) CreatePost(dto *CreatePostDto) (*models.Post, error) {
 		TextContent: filteredText,
 		MediaURLs:   datatypes.JSON(mediaUrlsJSON),
 		CoverURL:    dto.Cover,
-		Status:      "draft",
+		Status:      models.PostStatusDraft,
+		Anonymous:   dto.Anonymous,
 	}
 	if dto.Status != "" {
 		post.Status = dto.Status
@@ -154,7 +194,7 @@ func (s *service) CreatePost(dto *CreatePostDto) (*models.Post, error) {
 		return nil, err
 	}
 
-	return s.repo.FindByID(post.ID)
+	return s.present(s.repo.FindByID(post.ID))
 }
 
 // GetPost retrieves a single post by ID.
@@ -163,25 +203,63 @@ func (s *service) GetPost(id uint, currentUserID *uint) (*models.Post, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.fillPostLikeInfo(post, currentUserID)
+	fillLikeInfo(s.likeRepo, []*models.Post{post}, currentUserID)
+	s.anonymizer.presentPost(post)
 	return post, nil
 }
 
-func (s *service) fillPostLikeInfo(post *models.Post, currentUserID *uint) {
-	count, _ := s.likeRepo.CountByPost(post.ID)
-	post.LikesCount = count
-	if currentUserID != nil {
-		liked, _ := s.likeRepo.FindByUserAndPost(*currentUserID, post.ID)
-		post.IsLiked = liked != nil
+// GetAuthor returns the real author of a post without anonymizing it.
+func (s *service) GetAuthor(id uint) (*models.User, error) {
+	post, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return post.User, nil
+}
+
+// present converts a freshly loaded post into its public form.
+func (s *service) present(post *models.Post, err error) (*models.Post, error) {
+	if err != nil {
+		return nil, err
+	}
+	s.anonymizer.presentPost(post)
+	return post, nil
+}
+
+// fillLikeInfo 填充当前用户对一组帖子的点赞状态。点赞数已保存在帖子的计数列中，
+// 整页帖子只需一次 IN 查询。
+func fillLikeInfo(likeRepo LikeRepository, posts []*models.Post, currentUserID *uint) {
+	if currentUserID == nil || len(posts) == 0 {
+		return
+	}
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	liked, err := likeRepo.FindLikedPostIDs(*currentUserID, ids)
+	if err != nil {
+		log.Printf("Failed to load like state: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.IsLiked = liked[post.ID]
 	}
 }
 
 // UpdatePost handles updating an existing post.
-func (s *service) UpdatePost(id uint, dto *UpdatePostDto) (*models.Post, error) {
+func (s *service) UpdatePost(id uint, actor Actor, dto *UpdatePostDto) (*models.Post, error) {
 	post, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := authorizePostUpdate(actor, post); err != nil {
+		return nil, err
+	}
+	if dto.TagID != nil {
+		if err := s.checkTagAllowed(actor.Role, dto.TagID); err != nil {
+			return nil, err
+		}
+	}
 
 	// Apply updates from DTO
 	if dto.TextContent != nil {
@@ -222,28 +300,29 @@ func (s *service) UpdatePost(id uint, dto *UpdatePostDto) (*models.Post, error)
 		post.Status = *dto.Status
 	}
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		// Update tag ID if provided
-		if dto.TagID != nil {
-			post.TagID = dto.TagID
-		}
-
-		if err := tx.Save(post).Error; err != nil {
-			return err
-		}
-
-		return nil
-	})
+	// Update tag ID if provided
+	if dto.TagID != nil {
+		post.TagID = dto.TagID
+	}
 
-	if err != nil {
+	// repo.Update leaves likes_count/comments_count alone, so likes and comments
+	// made while the post was being edited are not overwritten with the stale counts
+	if err := s.repo.Update(post); err != nil {
 		return nil, err
 	}
 
-	return s.repo.FindByID(post.ID)
+	return s.present(s.repo.FindByID(post.ID))
 }
 
 // DeletePost 处理根据ID对帖子进行软删除。
-func (s *service) DeletePost(id uint) error {
+func (s *service) DeletePost(id uint, actor Actor) error {
+	post, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := authorizePostDelete(actor, post); err != nil {
+		return err
+	}
 	return s.repo.Delete(id)
 }
 
@@ -255,10 +334,12 @@ func (s *service) ListPosts(userID uint, page, pageSize int, tagID *uint, curren
 	if pageSize < 1 {
 		pageSize = 10
 	}
-	posts, total, err := s.repo.FindAllByUserID(userID, page, pageSize, tagID)
+	includeAnonymous := currentUserID != nil && *currentUserID == userID
+	posts, total, err := s.repo.FindAllByUserID(userID, page, pageSize, tagID, includeAnonymous)
 	if err == nil {
+		fillLikeInfo(s.likeRepo, posts, currentUserID)
 		for _, post := range posts {
-			s.fillPostLikeInfo(post, currentUserID)
+			s.anonymizer.presentPost(post)
 		}
 	}
 	return posts, total, err
@@ -274,9 +355,30 @@ func (s *service) GetAllPosts(page, pageSize int, tagID *uint, currentUserID *ui
 	}
 	posts, total, err := s.repo.FindAll(page, pageSize, tagID)
 	if err == nil {
+		fillLikeInfo(s.likeRepo, posts, currentUserID)
 		for _, post := range posts {
-			s.fillPostLikeInfo(post, currentUserID)
+			s.anonymizer.presentPost(post)
 		}
 	}
 	return posts, total, err
 }
+
+// GetAllPostsByCursor 按游标检索所有用户的帖子，可选按 tag 过滤。
+func (s *service) GetAllPostsByCursor(cursor *pagination.Cursor, limit int, tagID *uint, currentUserID *uint) ([]*models.Post, string, error) {
+	if limit < 1 {
+		limit = 10
+	}
+	// 多取一条用于判断是否还有下一页
+	posts, err := s.repo.FindAllByCursor(cursor, limit+1, tagID)
+	if err != nil {
+		return nil, "", err
+	}
+	posts, next := pagination.Next(posts, limit, func(p *models.Post) (time.Time, uint) {
+		return p.CreatedAt, p.ID
+	})
+	fillLikeInfo(s.likeRepo, posts, currentUserID)
+	for _, post := range posts {
+		s.anonymizer.presentPost(post)
+	}
+	return posts, next, nil
+}
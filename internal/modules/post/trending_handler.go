@@ -0,0 +1,74 @@
+package post
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrendingHandler 处理热门榜请求
+type TrendingHandler struct {
+	service TrendingService
+}
+
+func NewTrendingHandler(service TrendingService) *TrendingHandler {
+	return &TrendingHandler{service: service}
+}
+
+// GetTrending 按热度返回近期的帖子
+// @Summary 热门帖子
+// @Description 返回统计窗口内发布的帖子，按点赞、评论和收藏随时间衰减后的热度排序。热度由后台任务定期更新。
+// @Tags posts
+// @Produce json
+// @Param window query string false "统计窗口，如 24h、7d (默认为配置的第一个窗口)"
+// @Param tag_id query int false "标签ID"
+// @Param page query int false "页码 (默认为1)"
+// @Param pageSize query int false "每页项目数 (默认为10，最大50)"
+// @Success 200 {object} gin.H{data=[]models.Post,page=int} "热门帖子列表"
+// @Failure 400 {object} gin.H "不支持的窗口或参数格式无效"
+// @Failure 500 {object} gin.H "内部服务器错误"
+// @Router /posts/trending [get]
+func (h *TrendingHandler) GetTrending(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 10
+	}
+
+	var tagIDPtr *uint
+	if tagIDStr := c.Query("tag_id"); tagIDStr != "" {
+		tagID, err := strconv.ParseUint(tagIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID格式"})
+			return
+		}
+		tagIDUint := uint(tagID)
+		tagIDPtr = &tagIDUint
+	}
+
+	var currentUserIDPtr *uint
+	if uid, exists := c.Get("userID"); exists {
+		userID := uid.(uint)
+		currentUserIDPtr = &userID
+	}
+
+	posts, err := h.service.GetTrending(c.Query("window"), tagIDPtr, page, pageSize, currentUserIDPtr)
+	if errors.Is(err, ErrUnknownTrendingWindow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取热门帖子失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": posts,
+		"page": page,
+	})
+}
@@ -0,0 +1,184 @@
+package post
+
+import (
+	"time"
+
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 各类互动在热度中的权重，帖子发布本身记为一次权重为 1 的互动，使新帖也能进入榜单
+const (
+	trendingWeightPost       = 1.0
+	trendingWeightLike       = 1.0
+	trendingWeightComment    = 2.0
+	trendingWeightCollection = 3.0
+)
+
+// trendingSources 参与热度计算的互动表及其权重
+var trendingSources = []struct {
+	table  string
+	weight float64
+}{
+	{"likes", trendingWeightLike},
+	{"comments", trendingWeightComment},
+	{"collections", trendingWeightCollection},
+}
+
+// trendingEvent 一次计入热度的互动
+type trendingEvent struct {
+	PostID    uint
+	CreatedAt time.Time
+	Weight    float64
+}
+
+// TrendingRepository 热门榜的数据访问
+type TrendingRepository interface {
+	// ListPostIDsSince 返回 since 之后发布的已发布帖子
+	ListPostIDsSince(since time.Time) ([]uint, error)
+	// ListActivePostIDs 返回 since 之后发布、且在 activeSince 之后发布或有新互动的已发布帖子
+	ListActivePostIDs(since, activeSince time.Time) ([]uint, error)
+	// ListEvents 返回帖子的发布以及全部点赞、评论和收藏
+	ListEvents(postIDs []uint) ([]trendingEvent, error)
+	// SaveScores 写入帖子在窗口内的热度，已有的分数被覆盖
+	SaveScores(period string, scores map[uint]float64) error
+	// DeleteStale 删除窗口内 since 之前发布或已删除帖子的分数
+	DeleteStale(period string, since time.Time) error
+	// FindTrending 按热度从高到低返回窗口内的一页帖子，可选按 tag 过滤
+	FindTrending(period string, since time.Time, tagID *uint, page, pageSize int) ([]*models.Post, error)
+}
+
+type trendingRepository struct {
+	db *gorm.DB
+}
+
+func NewTrendingRepository(db *gorm.DB) TrendingRepository {
+	return &trendingRepository{db: db}
+}
+
+func (r *trendingRepository) ListPostIDsSince(since time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Post{}).
+		Where("created_at >= ? AND status = ?", since, models.PostStatusPublished).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *trendingRepository) ListActivePostIDs(since, activeSince time.Time) ([]uint, error) {
+	seen := make(map[uint]bool)
+	var ids []uint
+	add := func(batch []uint) {
+		for _, id := range batch {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	var created []uint
+	if err := r.db.Model(&models.Post{}).
+		Where("created_at >= ? AND status = ?", maxTime(since, activeSince), models.PostStatusPublished).
+		Pluck("id", &created).Error; err != nil {
+		return nil, err
+	}
+	add(created)
+
+	for _, source := range trendingSources {
+		var active []uint
+		err := r.db.Table(source.table).
+			Joins("JOIN posts ON posts.id = "+source.table+".post_id").
+			Where(source.table+".created_at >= ? AND "+source.table+".deleted_at IS NULL", activeSince).
+			Where("posts.created_at >= ? AND posts.status = ? AND posts.deleted_at IS NULL", since, models.PostStatusPublished).
+			Distinct(source.table+".post_id").
+			Pluck(source.table+".post_id", &active).Error
+		if err != nil {
+			return nil, err
+		}
+		add(active)
+	}
+	return ids, nil
+}
+
+func (r *trendingRepository) ListEvents(postIDs []uint) ([]trendingEvent, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+
+	var events []trendingEvent
+	var posts []trendingEvent
+	if err := r.db.Model(&models.Post{}).
+		Select("id AS post_id, created_at").
+		Where("id IN ?", postIDs).
+		Scan(&posts).Error; err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		post.Weight = trendingWeightPost
+		events = append(events, post)
+	}
+
+	for _, source := range trendingSources {
+		var batch []trendingEvent
+		if err := r.db.Table(source.table).
+			Select("post_id, created_at").
+			Where("post_id IN ? AND deleted_at IS NULL", postIDs).
+			Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, event := range batch {
+			event.Weight = source.weight
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *trendingRepository) SaveScores(period string, scores map[uint]float64) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]models.PostScore, 0, len(scores))
+	for postID, score := range scores {
+		rows = append(rows, models.PostScore{PostID: postID, Period: period, Score: score, UpdatedAt: now})
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+func (r *trendingRepository) DeleteStale(period string, since time.Time) error {
+	stale := r.db.Unscoped().Model(&models.Post{}).Select("id").
+		Where("created_at < ? OR deleted_at IS NOT NULL OR status <> ?", since, models.PostStatusPublished)
+	return r.db.Where("period = ? AND post_id IN (?)", period, stale).Delete(&models.PostScore{}).Error
+}
+
+func (r *trendingRepository) FindTrending(period string, since time.Time, tagID *uint, page, pageSize int) ([]*models.Post, error) {
+	var posts []*models.Post
+
+	// 分数可能尚未清理，发布时间、状态和删除标记以帖子表为准
+	query := r.db.Model(&models.Post{}).
+		Select("posts.*").
+		Joins("JOIN post_scores ON post_scores.post_id = posts.id AND post_scores.period = ?", period).
+		Where("posts.created_at >= ? AND posts.status = ?", since, models.PostStatusPublished)
+	if tagID != nil {
+		query = query.Where("posts.tag_id = ?", *tagID)
+	}
+
+	err := query.Order("post_scores.score DESC").Order("posts.id DESC").
+		Preload("User").Preload("Tag").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&posts).Error
+	return posts, err
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,185 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+)
+
+// ErrUnknownTrendingWindow 表示请求的热门榜窗口未配置
+var ErrUnknownTrendingWindow = errors.New("不支持的热门榜窗口")
+
+// trendingBatchSize 每次计算热度的帖子数量
+const trendingBatchSize = 200
+
+// TrendingService 热门榜。
+//
+// 热度采用前向衰减：每次互动贡献 权重 × 2^((互动时间 − 基准时间) / 半衰期)，
+// 越新的互动贡献越大，效果等同于所有互动随时间按半衰期衰减，但已有帖子的分数不需要随时间重算，
+// 只有出现新互动的帖子需要更新。为避免溢出，分数以 log2 形式保存。
+type TrendingService interface {
+	// GetTrending 返回指定窗口内按热度排序的一页帖子，window 为空时使用默认窗口
+	GetTrending(window string, tagID *uint, page, pageSize int, currentUserID *uint) ([]*models.Post, error)
+	// Run 定期更新热度，直到 ctx 结束
+	Run(ctx context.Context)
+	// RunPending 更新有新互动的帖子的热度，到达全量间隔时重算窗口内所有帖子
+	RunPending(ctx context.Context)
+}
+
+type trendingService struct {
+	repo       TrendingRepository
+	likeRepo   LikeRepository
+	anonymizer *Anonymizer
+	cfg        configs.TrendingConfig
+
+	// 以下状态只由 RunPending 读写，RunPending 只在 Run 的协程中调用
+	lastRun  time.Time
+	lastFull time.Time
+}
+
+func NewTrendingService(repo TrendingRepository, likeRepo LikeRepository, anonymizer *Anonymizer, cfg configs.TrendingConfig) TrendingService {
+	return &trendingService{
+		repo:       repo,
+		likeRepo:   likeRepo,
+		anonymizer: anonymizer,
+		cfg:        cfg,
+	}
+}
+
+func (s *trendingService) GetTrending(window string, tagID *uint, page, pageSize int, currentUserID *uint) ([]*models.Post, error) {
+	w, ok := s.window(window)
+	if !ok {
+		return nil, ErrUnknownTrendingWindow
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	posts, err := s.repo.FindTrending(w.Name, time.Now().Add(-w.Duration), tagID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	fillLikeInfo(s.likeRepo, posts, currentUserID)
+	for _, post := range posts {
+		s.anonymizer.presentPost(post)
+	}
+	return posts, nil
+}
+
+func (s *trendingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *trendingService) RunPending(ctx context.Context) {
+	now := time.Now()
+	// 启动后第一次执行时全量计算；取消点赞、删除评论不会留下记录，也依赖定期全量计算来反映
+	full := s.lastFull.IsZero() || now.Sub(s.lastFull) >= s.cfg.FullRefreshInterval
+
+	for _, w := range s.cfg.Windows {
+		if ctx.Err() != nil {
+			return
+		}
+		since := now.Add(-w.Duration)
+
+		var ids []uint
+		var err error
+		if full {
+			ids, err = s.repo.ListPostIDsSince(since)
+		} else {
+			ids, err = s.repo.ListActivePostIDs(since, s.lastRun)
+		}
+		if err != nil {
+			log.Printf("Failed to list trending posts for %s: %v", w.Name, err)
+			return
+		}
+
+		for start := 0; start < len(ids); start += trendingBatchSize {
+			end := min(start+trendingBatchSize, len(ids))
+			if err := s.refresh(w, ids[start:end]); err != nil {
+				log.Printf("Failed to update trending scores for %s: %v", w.Name, err)
+				return
+			}
+		}
+
+		if full {
+			if err := s.repo.DeleteStale(w.Name, since); err != nil {
+				log.Printf("Failed to delete stale trending scores for %s: %v", w.Name, err)
+			}
+		}
+	}
+
+	// 本轮开始之后的互动留给下一轮处理，两轮之间的互动不会遗漏
+	s.lastRun = now
+	if full {
+		s.lastFull = now
+	}
+}
+
+// refresh 根据帖子的全部互动重新计算热度
+func (s *trendingService) refresh(w configs.TrendingWindow, postIDs []uint) error {
+	events, err := s.repo.ListEvents(postIDs)
+	if err != nil {
+		return err
+	}
+
+	byPost := make(map[uint][]trendingEvent)
+	for _, event := range events {
+		byPost[event.PostID] = append(byPost[event.PostID], event)
+	}
+	scores := make(map[uint]float64, len(byPost))
+	for postID, postEvents := range byPost {
+		scores[postID] = trendingScore(postEvents, w.HalfLife)
+	}
+	return s.repo.SaveScores(w.Name, scores)
+}
+
+// window 查找配置的窗口，name 为空时返回默认窗口
+func (s *trendingService) window(name string) (configs.TrendingWindow, bool) {
+	if len(s.cfg.Windows) == 0 {
+		return configs.TrendingWindow{}, false
+	}
+	if name == "" {
+		return s.cfg.Windows[0], true
+	}
+	for _, w := range s.cfg.Windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return configs.TrendingWindow{}, false
+}
+
+// trendingScore 计算 log2(Σ 权重 × 2^(互动时间 / 半衰期))，时间以 Unix 纪元为基准。
+// 先减去最大的指数再求和，避免 2 的幂溢出。
+func trendingScore(events []trendingEvent, halfLife time.Duration) float64 {
+	exponents := make([]float64, len(events))
+	top := math.Inf(-1)
+	for i, event := range events {
+		exponents[i] = float64(event.CreatedAt.UnixNano())/float64(halfLife) + math.Log2(event.Weight)
+		top = math.Max(top, exponents[i])
+	}
+
+	var sum float64
+	for _, exponent := range exponents {
+		sum += math.Exp2(exponent - top)
+	}
+	return top + math.Log2(sum)
+}
@@ -0,0 +1,101 @@
+package post
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go-tree-hollow/configs"
+)
+
+func TestTrendingScore(t *testing.T) {
+	halfLife := 12 * time.Hour
+	base := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration, weight float64) trendingEvent {
+		return trendingEvent{PostID: 1, CreatedAt: base.Add(offset), Weight: weight}
+	}
+	// 以 base 时刻一次权重为 1 的互动为基准
+	ref := float64(base.UnixNano()) / float64(halfLife)
+
+	tests := []struct {
+		name   string
+		events []trendingEvent
+		want   float64 // 相对基准的 log2 分数
+	}{
+		{"一次互动", []trendingEvent{at(0, 1)}, 0},
+		{"两次同时的互动加倍", []trendingEvent{at(0, 1), at(0, 1)}, 1},
+		{"权重为 2 等同两次互动", []trendingEvent{at(0, 2)}, 1},
+		{"晚一个半衰期的互动贡献加倍", []trendingEvent{at(halfLife, 1)}, 1},
+		{"早一个半衰期的互动贡献减半", []trendingEvent{at(-halfLife, 1)}, -1},
+		{"不同时间的互动相加", []trendingEvent{at(0, 1), at(-halfLife, 2)}, 1},
+		{"评论和收藏的权重", []trendingEvent{at(0, trendingWeightComment), at(0, trendingWeightCollection), at(0, trendingWeightPost)}, math.Log2(6)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trendingScore(tt.events, halfLife) - ref
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("trendingScore = ref%+.6f, want ref%+.6f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrendingScoreOrdering(t *testing.T) {
+	halfLife := 12 * time.Hour
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	events := func(ages ...time.Duration) []trendingEvent {
+		result := make([]trendingEvent, len(ages))
+		for i, age := range ages {
+			result[i] = trendingEvent{CreatedAt: now.Add(-age), Weight: trendingWeightLike}
+		}
+		return result
+	}
+
+	// 三个赞都在一天前的帖子，不如两个赞都在刚才的帖子
+	old := trendingScore(events(24*time.Hour, 24*time.Hour, 24*time.Hour), halfLife)
+	fresh := trendingScore(events(0, 0), halfLife)
+	if old >= fresh {
+		t.Errorf("old score %f should be below fresh score %f", old, fresh)
+	}
+
+	// 同样的时间，互动多的分数更高
+	if more, fewer := trendingScore(events(time.Hour, time.Hour), halfLife), trendingScore(events(time.Hour), halfLife); more <= fewer {
+		t.Errorf("score with more likes %f should be above %f", more, fewer)
+	}
+}
+
+func TestTrendingScoreDoesNotOverflow(t *testing.T) {
+	// 半衰期很短时指数远超 float64 的表示范围，分数仍应是有限值
+	events := []trendingEvent{
+		{CreatedAt: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), Weight: 1},
+		{CreatedAt: time.Date(2026, 10, 16, 0, 0, 1, 0, time.UTC), Weight: 1},
+	}
+	score := trendingScore(events, time.Millisecond)
+	if math.IsInf(score, 0) || math.IsNaN(score) {
+		t.Fatalf("trendingScore = %v, want a finite value", score)
+	}
+}
+
+func TestTrendingWindow(t *testing.T) {
+	s := &trendingService{cfg: configs.TrendingConfig{Windows: []configs.TrendingWindow{
+		{Name: "24h", Duration: 24 * time.Hour},
+		{Name: "7d", Duration: 7 * 24 * time.Hour},
+	}}}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"", "24h", true},
+		{"24h", "24h", true},
+		{"7d", "7d", true},
+		{"30d", "", false},
+	}
+	for _, tt := range tests {
+		w, ok := s.window(tt.name)
+		if ok != tt.wantOK || w.Name != tt.want {
+			t.Errorf("window(%q) = (%q, %v), want (%q, %v)", tt.name, w.Name, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go-tree-hollow/internal/modules/email"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler 处理数据导出和账号注销相关的 HTTP 请求
+type AccountHandler struct {
+	service *AccountService
+}
+
+// NewAccountHandler 创建新的 AccountHandler 实例
+func NewAccountHandler(service *AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// RequestExport 申请导出个人数据
+func (h *AccountHandler) RequestExport(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := h.service.RequestExport(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListExports 查看导出任务
+func (h *AccountHandler) ListExports(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	exports, err := h.service.ListExports(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": exports})
+}
+
+// DownloadExport 下载导出文件
+func (h *AccountHandler) DownloadExport(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的导出任务ID"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	path, err := h.service.GetExportFile(userID.(uint), uint(exportID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrExportNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrExportNotReady):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.FileAttachment(path, fmt.Sprintf("tree-hollow-export-%d.zip", exportID))
+}
+
+// RequestDeletion 申请注销账号
+func (h *AccountHandler) RequestDeletion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.service.RequestDeletion(c.Request.Context(), userID.(uint), c.ClientIP(), &req)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			code = http.StatusNotFound
+		case errors.Is(err, ErrPasswordIncorrect), errors.Is(err, ErrGuestDeviceInvalid),
+			errors.Is(err, email.ErrCodeExpired), errors.Is(err, email.ErrCodeInvalid), errors.Is(err, email.ErrCodeAttemptsExceeded):
+			code = http.StatusUnauthorized
+		case errors.Is(err, ErrReauthRequired):
+			code = http.StatusBadRequest
+		case errors.Is(err, ErrDeletionAlreadyPending):
+			code = http.StatusConflict
+		case errors.Is(err, ErrTooManyReauth):
+			code = http.StatusTooManyRequests
+		}
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "已申请注销，宽限期内可随时撤销",
+		"scheduled_at": status.ScheduledAt,
+	})
+}
+
+// GetDeletionStatus 查询注销申请状态
+func (h *AccountHandler) GetDeletionStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status, err := h.service.GetDeletionStatus(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CancelDeletion 撤销注销申请
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.CancelDeletion(userID.(uint)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrDeletionNotScheduled):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销注销申请"})
+}
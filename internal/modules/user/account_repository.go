@@ -0,0 +1,182 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AccountRepository 负责数据导出任务和账号注销相关的数据访问
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// UserData 导出时收集的用户全部数据
+type UserData struct {
+	Profile       *models.User
+	Posts         []models.Post
+	Comments      []models.Comment
+	Likes         []models.Like
+	Collections   []models.Collection
+	Follows       []models.Follow
+	Conversations []models.Conversation
+	Messages      []models.Message
+}
+
+// CreateExport 创建导出任务
+func (r *AccountRepository) CreateExport(export *models.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+// GetExport 根据ID获取导出任务
+func (r *AccountRepository) GetExport(id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.First(&export, id).Error
+	return &export, err
+}
+
+// GetActiveExport 获取用户尚未完成的导出任务
+func (r *AccountRepository) GetActiveExport(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Where("user_id = ? AND status IN ?", userID,
+		[]string{models.ExportStatusPending, models.ExportStatusProcessing}).
+		First(&export).Error
+	return &export, err
+}
+
+// ListExports 获取用户的导出任务，最新的在前
+func (r *AccountRepository) ListExports(userID uint) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Find(&exports).Error
+	return exports, err
+}
+
+// ListPendingExports 获取等待处理的导出任务
+func (r *AccountRepository) ListPendingExports(limit int) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.Where("status = ?", models.ExportStatusPending).
+		Order("created_at asc").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// ClaimExport 将任务从 pending 改为 processing，返回 false 表示已被其他进程处理
+func (r *AccountRepository) ClaimExport(id uint) (bool, error) {
+	result := r.db.Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", id, models.ExportStatusPending).
+		Update("status", models.ExportStatusProcessing)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateExport 更新导出任务的指定字段
+func (r *AccountRepository) UpdateExport(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.DataExport{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// ListExpiredExports 获取已过期的导出文件
+func (r *AccountRepository) ListExpiredExports(now time.Time) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.Where("status = ? AND expires_at <= ?", models.ExportStatusReady, now).Find(&exports).Error
+	return exports, err
+}
+
+// DeleteExport 删除导出任务记录
+func (r *AccountRepository) DeleteExport(id uint) error {
+	return r.db.Unscoped().Delete(&models.DataExport{}, id).Error
+}
+
+// CollectUserData 读取用户的全部数据用于导出
+func (r *AccountRepository) CollectUserData(userID uint) (*UserData, error) {
+	data := &UserData{Profile: &models.User{}}
+	if err := r.db.First(data.Profile, userID).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&data.Posts, r.db.Where("user_id = ?", userID).Preload("Tag")},
+		{&data.Comments, r.db.Where("user_id = ?", userID)},
+		{&data.Likes, r.db.Where("user_id = ?", userID)},
+		{&data.Collections, r.db.Where("user_id = ?", userID)},
+		{&data.Follows, r.db.Where("follower_id = ? OR followed_id = ?", userID, userID)},
+		{&data.Conversations, r.db.Where("user1_id = ? OR user2_id = ?", userID, userID)},
+		{&data.Messages, r.db.Where("sender_id = ? OR receiver_id = ?", userID, userID)},
+	}
+	for _, q := range queries {
+		if err := q.query.Order("created_at asc").Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// ScheduleDeletion 记录用户的注销时间，at 为空表示撤销注销
+func (r *AccountRepository) ScheduleDeletion(userID uint, at *time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("deletion_scheduled_at", at).Error
+}
+
+// ListDueDeletions 获取宽限期已结束、需要执行删除的用户
+func (r *AccountRepository) ListDueDeletions(now time.Time, limit int) ([]*models.User, error) {
+	var users []*models.User
+	err := r.db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// PurgeUser 在同一事务中彻底删除用户及其产生的全部数据：
+// 用户的帖子连同帖子下他人的点赞、评论和收藏，用户在他人帖子下的点赞、评论和收藏，
+// 关注关系、私信会话和消息，以及登录会话、第三方身份和导出记录。
+// 数据均为物理删除，不保留软删除记录。
+func (r *AccountRepository) PurgeUser(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		postIDs := tx.Model(&models.Post{}).Select("id").Where("user_id = ?", userID)
+
+		// 该用户在他人帖子下的点赞和评论随后会被删除，先同步减少这些帖子的计数
+		for _, counter := range []struct{ column, table string }{
+			{"likes_count", "likes"},
+			{"comments_count", "comments"},
+		} {
+			sql := fmt.Sprintf(`UPDATE posts SET %[1]s = %[1]s - (
+				SELECT COUNT(*) FROM %[2]s WHERE %[2]s.post_id = posts.id AND %[2]s.user_id = ? AND %[2]s.deleted_at IS NULL
+			) WHERE user_id <> ? AND id IN (SELECT post_id FROM %[2]s WHERE user_id = ?)`, counter.column, counter.table)
+			if err := tx.Exec(sql, userID, userID, userID).Error; err != nil {
+				return err
+			}
+		}
+
+		steps := []struct {
+			model interface{}
+			query string
+			args  []interface{}
+		}{
+			{&models.Like{}, "user_id = ? OR post_id IN (?)", []interface{}{userID, postIDs}},
+			{&models.Comment{}, "user_id = ? OR post_id IN (?)", []interface{}{userID, postIDs}},
+			{&models.Collection{}, "user_id = ? OR post_id IN (?)", []interface{}{userID, postIDs}},
+			{&models.Post{}, "user_id = ?", []interface{}{userID}},
+			{&models.Follow{}, "follower_id = ? OR followed_id = ?", []interface{}{userID, userID}},
+			// 会话引用了最后一条消息，需先于消息删除
+			{&models.Conversation{}, "user1_id = ? OR user2_id = ?", []interface{}{userID, userID}},
+			{&models.Message{}, "sender_id = ? OR receiver_id = ?", []interface{}{userID, userID}},
+			{&models.RefreshToken{}, "user_id = ?", []interface{}{userID}},
+			{&models.Session{}, "user_id = ?", []interface{}{userID}},
+			{&models.UserIdentity{}, "user_id = ?", []interface{}{userID}},
+			{&models.DataExport{}, "user_id = ?", []interface{}{userID}},
+		}
+		for _, step := range steps {
+			if err := tx.Where(step.query, step.args...).Delete(step.model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.User{}, userID).Error
+	})
+}
@@ -0,0 +1,345 @@
+package user
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrExportNotFound         = errors.New("导出任务不存在")
+	ErrExportNotReady         = errors.New("导出文件尚未生成或已过期")
+	ErrPasswordIncorrect      = errors.New("密码错误")
+	ErrDeletionNotScheduled   = errors.New("账号未申请注销")
+	ErrDeletionAlreadyPending = errors.New("账号已申请注销")
+)
+
+// accountBatchSize 后台任务每轮处理的最大数量
+const accountBatchSize = 20
+
+// DeleteAccountRequest 申请注销账号请求
+type DeleteAccountRequest struct {
+	Password string `json:"password"`  // 再次确认身份，没有密码的账号改用 code
+	Code     string `json:"code"`      // 通过 /users/reauth/code 获取的身份确认验证码
+	DeviceID string `json:"device_id"` // 游客账号使用登录时的设备标识确认身份
+}
+
+// DeletionStatusResponse 注销申请状态
+type DeletionStatusResponse struct {
+	ScheduledAt *time.Time `json:"scheduled_at"` // 为空表示未申请注销
+}
+
+// AccountService 处理数据导出和账号注销
+type AccountService struct {
+	repo   *AccountRepository
+	users  *Repository
+	tokens TokenRevoker
+	guard  PasswordGuard
+	codes  email.EmailService
+	cfg    configs.AccountConfig
+}
+
+func NewAccountService(repo *AccountRepository, users *Repository, tokens TokenRevoker, guard PasswordGuard, codes email.EmailService, cfg configs.AccountConfig) *AccountService {
+	return &AccountService{
+		repo:   repo,
+		users:  users,
+		tokens: tokens,
+		guard:  guard,
+		codes:  codes,
+		cfg:    cfg,
+	}
+}
+
+// RequestExport 创建数据导出任务并立即在后台生成，已有未完成的任务时直接返回该任务
+func (s *AccountService) RequestExport(userID uint) (*models.DataExport, error) {
+	if active, err := s.repo.GetActiveExport(userID); err == nil {
+		return active, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("获取导出任务失败")
+	}
+
+	export := &models.DataExport{UserID: userID, Status: models.ExportStatusPending}
+	if err := s.repo.CreateExport(export); err != nil {
+		return nil, errors.New("创建导出任务失败")
+	}
+
+	go s.processExport(export.ID)
+	return export, nil
+}
+
+// ListExports 列出用户的导出任务
+func (s *AccountService) ListExports(userID uint) ([]*models.DataExport, error) {
+	exports, err := s.repo.ListExports(userID)
+	if err != nil {
+		return nil, errors.New("获取导出任务失败")
+	}
+	return exports, nil
+}
+
+// GetExportFile 返回可下载的导出文件路径
+func (s *AccountService) GetExportFile(userID, exportID uint) (string, error) {
+	export, err := s.repo.GetExport(exportID)
+	if err != nil || export.UserID != userID {
+		return "", ErrExportNotFound
+	}
+	if export.Status != models.ExportStatusReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return "", ErrExportNotReady
+	}
+	return export.FilePath, nil
+}
+
+// RequestDeletion 确认身份后申请注销，宽限期结束后账号及其数据将被删除
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uint, ip string, req *DeleteAccountRequest) (*DeletionStatusResponse, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.DeletionScheduledAt != nil {
+		return nil, ErrDeletionAlreadyPending
+	}
+	if err := verifyIdentity(ctx, s.codes, s.guard, user, identityProof{Password: req.Password, Code: req.Code, DeviceID: req.DeviceID, IP: ip}); err != nil {
+		return nil, err
+	}
+
+	scheduledAt := time.Now().Add(s.cfg.DeletionGracePeriod)
+	if err := s.repo.ScheduleDeletion(userID, &scheduledAt); err != nil {
+		return nil, errors.New("申请注销失败")
+	}
+	return &DeletionStatusResponse{ScheduledAt: &scheduledAt}, nil
+}
+
+// CancelDeletion 在宽限期内撤销注销申请
+func (s *AccountService) CancelDeletion(userID uint) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+	if err := s.repo.ScheduleDeletion(userID, nil); err != nil {
+		return errors.New("撤销注销失败")
+	}
+	return nil
+}
+
+// GetDeletionStatus 查询注销申请状态
+func (s *AccountService) GetDeletionStatus(userID uint) (*DeletionStatusResponse, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	return &DeletionStatusResponse{ScheduledAt: user.DeletionScheduledAt}, nil
+}
+
+// Run 定期执行后台任务，直到 ctx 结束
+func (s *AccountService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunPending 处理遗留的导出任务、到期的注销申请和过期的导出文件，由后台任务定期调用
+func (s *AccountService) RunPending(ctx context.Context) {
+	if exports, err := s.repo.ListPendingExports(accountBatchSize); err != nil {
+		log.Printf("Failed to list pending exports: %v", err)
+	} else {
+		for _, export := range exports {
+			s.processExport(export.ID)
+		}
+	}
+
+	if users, err := s.repo.ListDueDeletions(time.Now(), accountBatchSize); err != nil {
+		log.Printf("Failed to list due account deletions: %v", err)
+	} else {
+		for _, user := range users {
+			if err := s.purgeUser(ctx, user); err != nil {
+				log.Printf("Failed to delete account %d: %v", user.ID, err)
+			}
+		}
+	}
+
+	if exports, err := s.repo.ListExpiredExports(time.Now()); err != nil {
+		log.Printf("Failed to list expired exports: %v", err)
+	} else {
+		for _, export := range exports {
+			os.Remove(export.FilePath)
+			s.repo.DeleteExport(export.ID)
+		}
+	}
+}
+
+// processExport 生成导出文件，同一任务只会被一个调用方处理
+func (s *AccountService) processExport(exportID uint) {
+	claimed, err := s.repo.ClaimExport(exportID)
+	if err != nil || !claimed {
+		return
+	}
+	export, err := s.repo.GetExport(exportID)
+	if err != nil {
+		return
+	}
+
+	path, err := s.writeArchive(export)
+	if err != nil {
+		log.Printf("Failed to build export %d: %v", exportID, err)
+		s.repo.UpdateExport(exportID, map[string]interface{}{
+			"status": models.ExportStatusFailed,
+			"error":  "生成导出文件失败",
+		})
+		return
+	}
+
+	now := time.Now()
+	s.repo.UpdateExport(exportID, map[string]interface{}{
+		"status":       models.ExportStatusReady,
+		"file_path":    path,
+		"completed_at": now,
+		"expires_at":   now.Add(s.cfg.ExportTTL),
+	})
+}
+
+// writeArchive 将用户数据按类别写入 ZIP 文件，每个类别一个 JSON 文件
+func (s *AccountService) writeArchive(export *models.DataExport) (string, error) {
+	data, err := s.repo.CollectUserData(export.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.cfg.ExportDir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.cfg.ExportDir, fmt.Sprintf("export-%d-%d.zip", export.UserID, export.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	entries := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", exportProfile(data.Profile)},
+		{"posts.json", data.Posts},
+		{"comments.json", data.Comments},
+		{"likes.json", data.Likes},
+		{"collections.json", data.Collections},
+		{"follows.json", data.Follows},
+		{"conversations.json", data.Conversations},
+		{"messages.json", data.Messages},
+	}
+	for _, entry := range entries {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			return "", err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entry.value); err != nil {
+			return "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// purgeUser 删除用户及其全部数据，并清理其上传的本地文件
+func (s *AccountService) purgeUser(ctx context.Context, user *models.User) error {
+	data, err := s.repo.CollectUserData(user.ID)
+	if err != nil {
+		return err
+	}
+	exports, _ := s.repo.ListExports(user.ID)
+
+	// 先让已签发的令牌失效，避免删除过程中继续写入数据
+	if err := s.tokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.repo.PurgeUser(user.ID); err != nil {
+		return err
+	}
+
+	for _, path := range uploadedFiles(data) {
+		os.Remove(path)
+	}
+	for _, export := range exports {
+		if export.FilePath != "" {
+			os.Remove(export.FilePath)
+		}
+	}
+	log.Printf("Account %d deleted", user.ID)
+	return nil
+}
+
+// exportProfile 导出的个人资料，包含 json 中默认隐藏的账号状态字段
+func exportProfile(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                    user.ID,
+		"email":                 user.Email,
+		"nickname":              user.Nickname,
+		"avatar_url":            user.AvatarURL,
+		"background_url":        user.BackgroundURL,
+		"birthday":              user.Birthday,
+		"bio":                   user.Bio,
+		"location":              user.Location,
+		"role":                  user.Role,
+		"locale":                user.Locale,
+		"digest_enabled":        !user.DigestOptOut,
+		"email_verified_at":     user.EmailVerifiedAt,
+		"two_factor_enabled":    user.IsTwoFactorEnabled(),
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+		"created_at":            user.CreatedAt,
+		"updated_at":            user.UpdatedAt,
+	}
+}
+
+// uploadedFiles 收集用户资料和帖子中引用的本地上传文件
+func uploadedFiles(data *UserData) []string {
+	urls := []string{data.Profile.AvatarURL, data.Profile.BackgroundURL}
+	for _, post := range data.Posts {
+		urls = append(urls, post.CoverURL)
+		var media []string
+		if len(post.MediaURLs) > 0 && json.Unmarshal(post.MediaURLs, &media) == nil {
+			urls = append(urls, media...)
+		}
+	}
+
+	var paths []string
+	for _, u := range urls {
+		// 只处理本服务 /uploads 目录下的文件，外部链接忽略
+		if !strings.HasPrefix(u, "/uploads/") {
+			continue
+		}
+		name := filepath.Base(u)
+		if name == "." || name == "/" || name == ".." {
+			continue
+		}
+		paths = append(paths, filepath.Join("uploads", name))
+	}
+	return paths
+}
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage 退订确认页。打开链接只展示确认按钮，点击后才 POST 退订，
+// 避免邮件安全网关、杀毒软件预先访问链接时替用户退订
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>退订每周动态</title>
+</head>
+<body>
+{{- if .Error}}
+<p>{{.Error}}</p>
+{{- else if .Done}}
+<p>已退订每周动态，可在个人资料中重新开启。</p>
+{{- else}}
+<p>确认不再接收每周动态摘要邮件？</p>
+<form method="post" action="{{.Action}}">
+<button type="submit">确认退订</button>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Action string // 确认按钮提交的地址
+	Done   bool
+	Error  string
+}
+
+// DigestHandler 处理动态摘要相关的 HTTP 请求
+type DigestHandler struct {
+	service *DigestService
+}
+
+// NewDigestHandler 创建新的 DigestHandler 实例
+func NewDigestHandler(service *DigestService) *DigestHandler {
+	return &DigestHandler{service: service}
+}
+
+// ConfirmUnsubscribe 打开邮件中的退订链接时展示确认页，不修改订阅状态
+func (h *DigestHandler) ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if err := h.service.CheckUnsubscribeToken(token); err != nil {
+		renderUnsubscribePage(c, http.StatusBadRequest, unsubscribePageData{Error: err.Error()})
+		return
+	}
+
+	renderUnsubscribePage(c, http.StatusOK, unsubscribePageData{
+		Action: c.Request.URL.Path + "?token=" + url.QueryEscape(token),
+	})
+}
+
+// Unsubscribe 退订动态摘要，无需登录。
+// 确认页的按钮和邮件客户端的一键退订（RFC 8058）都 POST 到这里；浏览器提交时返回页面，其他情况返回 JSON
+func (h *DigestHandler) Unsubscribe(c *gin.Context) {
+	html := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+
+	token := c.Query("token")
+	if token == "" {
+		err := ErrInvalidUnsubscribeToken
+		if html {
+			renderUnsubscribePage(c, http.StatusBadRequest, unsubscribePageData{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Unsubscribe(token); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidUnsubscribeToken) {
+			status = http.StatusBadRequest
+		}
+		if html {
+			renderUnsubscribePage(c, status, unsubscribePageData{Error: err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if html {
+		renderUnsubscribePage(c, http.StatusOK, unsubscribePageData{Done: true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已退订每周动态，可在个人资料中重新开启"})
+}
+
+func renderUnsubscribePage(c *gin.Context, status int, data unsubscribePageData) {
+	var buf bytes.Buffer
+	if err := unsubscribePage.Execute(&buf, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "页面渲染失败"})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
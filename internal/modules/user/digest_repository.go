@@ -0,0 +1,98 @@
+package user
+
+import (
+	"time"
+
+	"go-tree-hollow/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DigestRepository 负责每周动态摘要的数据访问
+type DigestRepository struct {
+	db *gorm.DB
+}
+
+func NewDigestRepository(db *gorm.DB) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+// Activity 用户在统计周期内收到的动态
+type Activity struct {
+	NewLikes       int64
+	NewComments    int64
+	NewFollowers   int64
+	UnreadMessages int64 // 当前全部未读私信，不限于统计周期
+}
+
+// IsEmpty 判断统计周期内是否没有任何动态
+func (a *Activity) IsEmpty() bool {
+	return a.NewLikes == 0 && a.NewComments == 0 && a.NewFollowers == 0 && a.UnreadMessages == 0
+}
+
+// ListDue 获取需要发送摘要的用户：邮箱已验证、未退订、非游客、未申请注销，
+// 且注册和上一次摘要都早于 cutoff
+func (r *DigestRepository) ListDue(cutoff time.Time, limit int) ([]*models.User, error) {
+	var users []*models.User
+	err := r.db.Where("digest_opt_out = ? AND role <> ? AND deletion_scheduled_at IS NULL", false, models.RoleGuest).
+		Where("email_verified_at IS NOT NULL").
+		Where("created_at <= ? AND (digest_sent_at IS NULL OR digest_sent_at <= ?)", cutoff, cutoff).
+		Order("id").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// ClaimDigest 记录本次摘要统计到的时间，返回 false 表示已被其他进程处理
+func (r *DigestRepository) ClaimDigest(user *models.User, sentAt time.Time) (bool, error) {
+	query := r.db.Model(&models.User{}).Where("id = ?", user.ID)
+	if user.DigestSentAt == nil {
+		query = query.Where("digest_sent_at IS NULL")
+	} else {
+		query = query.Where("digest_sent_at = ?", *user.DigestSentAt)
+	}
+	result := query.UpdateColumn("digest_sent_at", sentAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountActivity 统计用户在 [start, end) 内收到的点赞、评论和新关注，以及当前的未读私信，
+// 不计算用户自己的操作
+func (r *DigestRepository) CountActivity(userID uint, start, end time.Time) (*Activity, error) {
+	var activity Activity
+
+	if err := r.db.Model(&models.Like{}).
+		Joins("JOIN posts ON posts.id = likes.post_id AND posts.deleted_at IS NULL").
+		Where("posts.user_id = ? AND likes.user_id <> ?", userID, userID).
+		Where("likes.created_at >= ? AND likes.created_at < ?", start, end).
+		Count(&activity.NewLikes).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(&models.Comment{}).
+		Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+		Where("posts.user_id = ? AND comments.user_id <> ?", userID, userID).
+		Where("comments.created_at >= ? AND comments.created_at < ?", start, end).
+		Count(&activity.NewComments).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(&models.Follow{}).
+		Where("followed_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).
+		Count(&activity.NewFollowers).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(&models.Message{}).
+		Where("receiver_id = ? AND read_at IS NULL", userID).
+		Count(&activity.UnreadMessages).Error; err != nil {
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+// SetOptOut 设置是否退订动态摘要
+func (r *DigestRepository) SetOptOut(userID uint, optOut bool) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("digest_opt_out", optOut)
+	return result.RowsAffected > 0, result.Error
+}
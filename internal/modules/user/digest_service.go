@@ -0,0 +1,191 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-tree-hollow/configs"
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("退订链接无效")
+
+// digestBatchSize 每轮最多处理的用户数量
+const digestBatchSize = 100
+
+// DigestService 定期向用户发送动态摘要（收到的点赞、评论、新关注和未读私信）
+type DigestService struct {
+	repo      *DigestRepository
+	sender    *email.Sender
+	cfg       configs.DigestConfig
+	publicURL string
+	secret    []byte
+}
+
+// NewDigestService 创建动态摘要服务。cfg.Secret 为空时使用随机密钥，重启后已发出的退订链接会失效。
+func NewDigestService(repo *DigestRepository, sender *email.Sender, cfg configs.DigestConfig, publicURL string) *DigestService {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		log.Println("Warning: DIGEST_SECRET is not set, using a random key; unsubscribe links will stop working after restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate digest secret: %v", err)
+		}
+	}
+	return &DigestService{
+		repo:      repo,
+		sender:    sender,
+		cfg:       cfg,
+		publicURL: publicURL,
+		secret:    secret,
+	}
+}
+
+// Run 定期发送到期的动态摘要，直到 ctx 结束
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.WorkerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunPending 向距上一次摘要已满一个周期的用户发送摘要，由后台任务定期调用
+func (s *DigestService) RunPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		users, err := s.repo.ListDue(now.Add(-s.cfg.Period), digestBatchSize)
+		if err != nil {
+			log.Printf("Failed to list due digests: %v", err)
+			return
+		}
+
+		// 单个用户失败时记录日志并继续处理同一批的其他用户
+		failed := 0
+		for _, user := range users {
+			if err := s.sendDigest(user, now); err != nil {
+				log.Printf("Failed to send digest to user %d: %v", user.ID, err)
+				failed++
+			}
+		}
+		// 整批都失败时（如数据库不可用）这些用户仍然到期，留到下一轮再处理，避免反复取到同一批
+		if len(users) < digestBatchSize || failed == len(users) {
+			return
+		}
+	}
+}
+
+// sendDigest 统计上一次摘要以来的动态并发送，没有任何动态时不发送邮件
+func (s *DigestService) sendDigest(user *models.User, now time.Time) error {
+	start := now.Add(-s.cfg.Period)
+	if user.DigestSentAt != nil {
+		start = *user.DigestSentAt
+	}
+
+	// 先记录统计时间再发送，同一周期的摘要只会被一个进程发送
+	claimed, err := s.repo.ClaimDigest(user, now)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	activity, err := s.repo.CountActivity(user.ID, start, now)
+	if err != nil {
+		return err
+	}
+	if activity.IsEmpty() {
+		return nil
+	}
+
+	data := &email.DigestData{
+		Nickname:       user.Nickname,
+		PeriodStart:    start.Format("2006-01-02"),
+		PeriodEnd:      now.Format("2006-01-02"),
+		NewLikes:       activity.NewLikes,
+		NewComments:    activity.NewComments,
+		NewFollowers:   activity.NewFollowers,
+		UnreadMessages: activity.UnreadMessages,
+		UnsubscribeURL: s.UnsubscribeURL(user.ID),
+	}
+	// 邮件客户端据此显示退订按钮，并直接 POST 退订链接完成一键退订（RFC 8058）
+	headers := map[string]string{
+		"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return s.sender.SendTemplateWithHeaders(user.Email, user.Locale, email.TemplateDigest, headers, data)
+}
+
+// UnsubscribeURL 返回用户的退订链接，链接不会过期，无需登录即可使用
+func (s *DigestService) UnsubscribeURL(userID uint) string {
+	return s.publicURL + "/api/v1/users/digest/unsubscribe?token=" + url.QueryEscape(s.unsubscribeToken(userID))
+}
+
+// CheckUnsubscribeToken 校验退订链接中的令牌，不修改订阅状态
+func (s *DigestService) CheckUnsubscribeToken(token string) error {
+	if _, ok := s.parseUnsubscribeToken(token); !ok {
+		return ErrInvalidUnsubscribeToken
+	}
+	return nil
+}
+
+// Unsubscribe 校验退订链接中的令牌并退订动态摘要
+func (s *DigestService) Unsubscribe(token string) error {
+	userID, ok := s.parseUnsubscribeToken(token)
+	if !ok {
+		return ErrInvalidUnsubscribeToken
+	}
+	updated, err := s.repo.SetOptOut(userID, true)
+	if err != nil {
+		return errors.New("退订失败")
+	}
+	if !updated {
+		return ErrInvalidUnsubscribeToken
+	}
+	return nil
+}
+
+// unsubscribeToken 生成退订令牌：<用户ID>.<签名>
+func (s *DigestService) unsubscribeToken(userID uint) string {
+	return fmt.Sprintf("%d.%s", userID, base64.RawURLEncoding.EncodeToString(s.sign(userID)))
+}
+
+func (s *DigestService) parseUnsubscribeToken(token string) (uint, bool) {
+	id, sig, found := strings.Cut(token, ".")
+	if !found {
+		return 0, false
+	}
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sign(uint(userID))) {
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+func (s *DigestService) sign(userID uint) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "digest-unsubscribe:%d", userID)
+	return mac.Sum(nil)[:16]
+}
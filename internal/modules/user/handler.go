@@ -1,7 +1,11 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"go-tree-hollow/internal/modules/email"
 
 	"github.com/gin-gonic/gin"
 )
@@ -57,3 +61,162 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, profile)
 }
+
+// UpdateRole 修改用户角色（仅管理员）
+func (h *Handler) UpdateRole(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	operatorID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.UpdateRole(c.Request.Context(), operatorID.(uint), uint(targetID), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrInvalidRole), errors.Is(err, ErrCannotChangeOwnRole), errors.Is(err, ErrGuestAccount):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ChangePassword 修改密码
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ChangePassword(c.Request.Context(), userID.(uint), c.ClientIP(), &req); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrPasswordIncorrect), errors.Is(err, ErrReauthRequired), errors.Is(err, ErrGuestMustRegister),
+			errors.Is(err, email.ErrCodeExpired), errors.Is(err, email.ErrCodeInvalid), errors.Is(err, email.ErrCodeAttemptsExceeded):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrTooManyReauth):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已修改，请重新登录"})
+}
+
+// RequestEmailChange 申请更换邮箱，验证码发送到新邮箱
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.RequestEmailChange(c.Request.Context(), userID.(uint), c.ClientIP(), &req); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrPasswordIncorrect), errors.Is(err, ErrReauthRequired), errors.Is(err, ErrGuestMustRegister),
+			errors.Is(err, ErrEmailUnchanged), errors.Is(err, email.ErrInvalidEmail),
+			errors.Is(err, email.ErrCodeExpired), errors.Is(err, email.ErrCodeInvalid), errors.Is(err, email.ErrCodeAttemptsExceeded):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrEmailTaken):
+			status = http.StatusConflict
+		case errors.Is(err, email.ErrCodeSendTooFast), errors.Is(err, ErrTooManyReauth):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "验证码已发送至新邮箱"})
+}
+
+// SendReauthCode 向当前邮箱发送身份确认验证码，供没有密码的账号修改密码、更换邮箱和注销时使用
+func (h *Handler) SendReauthCode(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.service.SendReauthCode(c.Request.Context(), userID.(uint)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrGuestMustRegister), errors.Is(err, ErrEmailNotVerified), errors.Is(err, email.ErrInvalidEmail):
+			status = http.StatusBadRequest
+		case errors.Is(err, email.ErrCodeSendTooFast):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "验证码已发送至当前邮箱"})
+}
+
+// ConfirmEmailChange 校验验证码后更换邮箱
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ConfirmEmailChange(c.Request.Context(), userID.(uint), &req); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, email.ErrCodeExpired), errors.Is(err, email.ErrCodeInvalid), errors.Is(err, email.ErrCodeAttemptsExceeded):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrEmailTaken):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱已更换，请重新登录"})
+}
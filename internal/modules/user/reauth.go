@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
+	"go-tree-hollow/pkg/utils"
+)
+
+var (
+	ErrReauthRequired     = errors.New("请提供当前密码或邮箱验证码以确认身份")
+	ErrGuestMustRegister  = errors.New("游客账号请先注册")
+	ErrEmailNotVerified   = errors.New("邮箱尚未验证，无法发送验证码")
+	ErrGuestDeviceInvalid = errors.New("设备标识不匹配")
+	ErrTooManyReauth      = errors.New("密码错误次数过多，请稍后再试")
+)
+
+// PasswordGuard 限制密码的尝试次数，由认证模块的 LoginGuard 实现，
+// 与登录共用失败计数，避免通过身份确认接口绕过登录限制暴力破解密码
+type PasswordGuard interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string) (bool, error)
+	Reset(ctx context.Context, email string) error
+}
+
+// identityProof 修改密码、更换邮箱、注销账号等敏感操作前的身份确认，提供其中一项即可：
+//   - Password：当前密码
+//   - Code：通过 POST /users/reauth/code 发送到当前邮箱的验证码，
+//     验证码登录和第三方登录创建的账号没有可用的密码，使用这种方式
+//   - DeviceID：游客登录时使用的设备标识，游客账号没有邮箱和密码
+type identityProof struct {
+	Password string
+	Code     string
+	DeviceID string
+	IP       string // 请求来源，用于限制密码尝试次数
+}
+
+// verifyIdentity 按 proof 中提供的方式确认操作者身份
+func verifyIdentity(ctx context.Context, codes email.EmailService, guard PasswordGuard, user *models.User, proof identityProof) error {
+	switch {
+	case user.IsGuest():
+		if proof.DeviceID == "" {
+			return ErrReauthRequired
+		}
+		if user.GuestDeviceHash == nil ||
+			subtle.ConstantTimeCompare([]byte(utils.DeviceFingerprint(proof.DeviceID)), []byte(*user.GuestDeviceHash)) != 1 {
+			return ErrGuestDeviceInvalid
+		}
+		return nil
+	case proof.Code != "":
+		return codes.VerifyCode(ctx, email.PurposeReauth, reauthSubject(user.ID), proof.Code)
+	case proof.Password != "":
+		if err := guard.Check(ctx, user.Email, proof.IP); err != nil {
+			return ErrTooManyReauth
+		}
+		if !utils.CheckPassword(proof.Password, user.Password) {
+			guard.RecordFailure(ctx, user.Email, proof.IP)
+			return ErrPasswordIncorrect
+		}
+		guard.Reset(ctx, user.Email)
+		return nil
+	}
+	return ErrReauthRequired
+}
+
+// reauthSubject 身份确认验证码的存储键，验证码只对申请的用户有效
+func reauthSubject(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// SendReauthCode 向用户当前的邮箱发送身份确认验证码
+func (s *Service) SendReauthCode(ctx context.Context, userID uint) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return errors.New("获取用户信息失败")
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.IsGuest() {
+		return ErrGuestMustRegister
+	}
+	if !user.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+
+	return s.codes.SendVerificationCodeTo(ctx, email.PurposeReauth, reauthSubject(userID), user.Email, user.Locale)
+}
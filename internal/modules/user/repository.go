@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"go-tree-hollow/internal/models"
 
 	"gorm.io/gorm"
@@ -24,9 +26,34 @@ func (r *Repository) GetByID(id uint) (*models.User, error) {
 	return &user, err
 }
 
-// Update 更新用户信息
-func (r *Repository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+// UpdateFields 更新用户的指定字段
+func (r *Repository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// EmailExists 判断邮箱是否已被注册
+func (r *Repository) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdatePassword 更新用户密码（需传入已加密的密码）
+func (r *Repository) UpdatePassword(id uint, hashedPassword string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
+
+// UpdateEmail 更换邮箱并记录新邮箱的验证时间
+func (r *Repository) UpdateEmail(id uint, email string, verifiedAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": verifiedAt,
+	}).Error
+}
+
+// UpdateRole 更新用户角色
+func (r *Repository) UpdateRole(id uint, role string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
 }
 
 // GetFollowCount 获取关注数
@@ -69,12 +96,7 @@ func (r *Repository) GetReceivedCollectionCount(userID uint) (int64, error) {
 // GetUserPosts 获取用户发布的帖子列表
 func (r *Repository) GetUserPosts(userID uint) ([]models.Post, error) {
 	var posts []models.Post
-	// 预加载 User 和 Tag, 以及计算 LikesCount
-	// 注意：Post 结构体重 LikesCount 是 gorm:"-"，需要手动计算或者 SQL 映射
-	// 这里简化，只返回帖子基本信息，如果需要 LikesCount 可能需要额外处理
-	// 或者在 Post model 中如果 LikesCount 是数据库字段则直接取，如果是 gorm:"-" 则需要 service 层处理
-	// Post model definition shows LikesCount is -, let's populate it via subquery or separate call if needed.
-	// For now, standard find. Service can enhance it if needed.
+	// 预加载 User 和 Tag；LikesCount 和 CommentsCount 是帖子表的计数列，随帖子一起查出
 	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Preload("User").Preload("Tag").Find(&posts).Error
 	return posts, err
 }
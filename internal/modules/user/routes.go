@@ -1,18 +1,45 @@
 package user
 
 import (
-	"go-tree-hollow/internal/middleware"
-
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes 注册用户模块路由
-func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+// adminMiddleware 用于限制只有管理员才能访问用户管理接口
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler, accountHandler *AccountHandler, digestHandler *DigestHandler, authMiddleware, adminMiddleware gin.HandlerFunc) {
 	// 需要认证的用户相关路由
 	userGroup := router.Group("/users")
-	userGroup.Use(middleware.AuthRequired())
+	userGroup.Use(authMiddleware)
 	{
 		userGroup.GET("/profile", handler.GetProfile)
 		userGroup.PUT("/profile", handler.UpdateProfile)
+		userGroup.PUT("/password", handler.ChangePassword)           // PUT /api/v1/users/password - 修改密码
+		userGroup.POST("/email", handler.RequestEmailChange)         // POST /api/v1/users/email - 向新邮箱发送验证码
+		userGroup.POST("/email/confirm", handler.ConfirmEmailChange) // POST /api/v1/users/email/confirm - 确认更换邮箱
+		userGroup.POST("/reauth/code", handler.SendReauthCode)       // POST /api/v1/users/reauth/code - 发送身份确认验证码
+
+		// 数据导出
+		userGroup.POST("/export", accountHandler.RequestExport)              // POST /api/v1/users/export - 申请导出个人数据
+		userGroup.GET("/export", accountHandler.ListExports)                 // GET /api/v1/users/export - 查看导出任务
+		userGroup.GET("/export/:id/download", accountHandler.DownloadExport) // GET /api/v1/users/export/:id/download - 下载导出文件
+
+		// 注销账号
+		userGroup.POST("/deletion", accountHandler.RequestDeletion)  // POST /api/v1/users/deletion - 申请注销
+		userGroup.GET("/deletion", accountHandler.GetDeletionStatus) // GET /api/v1/users/deletion - 查询注销状态
+		userGroup.DELETE("/deletion", accountHandler.CancelDeletion) // DELETE /api/v1/users/deletion - 撤销注销
+	}
+
+	// 动态摘要退订链接（无需登录，通过链接中的签名识别用户）
+	digestGroup := router.Group("/users/digest")
+	{
+		digestGroup.GET("/unsubscribe", digestHandler.ConfirmUnsubscribe) // GET /api/v1/users/digest/unsubscribe?token= - 邮件中的退订链接，展示确认页
+		digestGroup.POST("/unsubscribe", digestHandler.Unsubscribe)       // POST /api/v1/users/digest/unsubscribe?token= - 确认退订，也用于邮件客户端的一键退订
+	}
+
+	// 用户管理（仅管理员）
+	adminGroup := router.Group("/admin/users")
+	adminGroup.Use(authMiddleware, adminMiddleware)
+	{
+		adminGroup.PUT("/:id/role", handler.UpdateRole) // PUT /api/v1/admin/users/:id/role - 修改用户角色
 	}
 }
@@ -1,17 +1,47 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"go-tree-hollow/internal/models"
+	"go-tree-hollow/internal/modules/email"
 	"go-tree-hollow/pkg/utils"
+	"log"
+	"strings"
+	"time"
 )
 
+var (
+	ErrUserNotFound        = errors.New("用户不存在")
+	ErrInvalidRole         = errors.New("无效的角色")
+	ErrGuestAccount        = errors.New("游客账号需先注册才能修改角色")
+	ErrCannotChangeOwnRole = errors.New("不能修改自己的角色")
+	ErrEmailTaken          = errors.New("该邮箱已被其他账号使用")
+	ErrEmailUnchanged      = errors.New("新邮箱与当前邮箱相同")
+)
+
+// TokenRevoker 使用户已签发的令牌失效，由认证模块实现
+type TokenRevoker interface {
+	RevokeAllForUser(ctx context.Context, userID uint) error
+}
+
 type Service struct {
-	repo *Repository
+	repo   *Repository
+	tokens TokenRevoker
+	guard  PasswordGuard
+	codes  email.EmailService // 更换邮箱验证码
+	sender *email.Sender
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, tokens TokenRevoker, guard PasswordGuard, codes email.EmailService, sender *email.Sender) *Service {
+	return &Service{
+		repo:   repo,
+		tokens: tokens,
+		guard:  guard,
+		codes:  codes,
+		sender: sender,
+	}
 }
 
 // ProfileResponse 用户信息响应
@@ -24,6 +54,34 @@ type ProfileResponse struct {
 	CreatedAt     string `json:"created_at"`
 	Bio           string `json:"bio"`
 	Location      string `json:"location"`
+	Role          string `json:"role"`
+	Locale        string `json:"locale"`
+	DigestEnabled bool   `json:"digest_enabled"` // 是否接收每周动态摘要
+}
+
+// UpdateRoleRequest 修改用户角色请求
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // 当前密码，没有密码的账号改用 code
+	Code            string `json:"code"`             // 通过 /users/reauth/code 获取的身份确认验证码
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangeEmailRequest 更换邮箱请求，验证码将发送到新邮箱
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password"` // 再次确认身份，没有密码的账号改用 code
+	Code     string `json:"code"`     // 通过 /users/reauth/code 获取的身份确认验证码
+}
+
+// ConfirmEmailRequest 确认更换邮箱请求
+type ConfirmEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Code     string `json:"code" binding:"required"`
 }
 
 // UpdateProfileRequest 更新用户信息请求
@@ -34,6 +92,8 @@ type UpdateProfileRequest struct {
 	Birthday      *string `json:"birthday"`
 	Bio           *string `json:"bio"`
 	Location      *string `json:"location"`
+	Locale        *string `json:"locale" binding:"omitempty,oneof=zh-CN en"` // 邮件通知使用的语言
+	DigestEnabled *bool   `json:"digest_enabled"`                            // 是否接收每周动态摘要
 }
 
 type MyProfileResponse struct {
@@ -82,6 +142,9 @@ func (s *Service) GetMyProfile(userID uint) (*MyProfileResponse, error) {
 			CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
 			Bio:           user.Bio,
 			Location:      user.Location,
+			Role:          user.Role,
+			Locale:        email.NormalizeLocale(user.Locale),
+			DigestEnabled: !user.DigestOptOut,
 		},
 		Birthday:       user.Birthday,
 		Age:            age,
@@ -113,6 +176,9 @@ func (s *Service) GetProfile(userID uint) (*ProfileResponse, error) {
 		CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
 		Bio:           user.Bio,
 		Location:      user.Location,
+		Role:          user.Role,
+		Locale:        email.NormalizeLocale(user.Locale),
+		DigestEnabled: !user.DigestOptOut,
 	}, nil
 }
 
@@ -126,30 +192,199 @@ func (s *Service) UpdateProfile(userID uint, req *UpdateProfileRequest) (*Profil
 		return nil, errors.New("用户不存在")
 	}
 
-	// 更新字段
+	// 只写入请求中提供的资料字段，不覆盖密码、邮箱、角色、两步验证等同时可能被修改的字段
+	fields := make(map[string]interface{})
 	if req.Nickname != nil {
-		user.Nickname = *req.Nickname
+		fields["nickname"] = *req.Nickname
 	}
 	if req.AvatarURL != nil {
-		user.AvatarURL = *req.AvatarURL
+		fields["avatar_url"] = *req.AvatarURL
 	}
 	if req.BackgroundURL != nil {
-		user.BackgroundURL = *req.BackgroundURL
+		fields["background_url"] = *req.BackgroundURL
 	}
 	if req.Birthday != nil {
-		user.Birthday = *req.Birthday
+		fields["birthday"] = *req.Birthday
 	}
 	if req.Bio != nil {
-		user.Bio = *req.Bio
+		fields["bio"] = *req.Bio
 	}
 	if req.Location != nil {
-		user.Location = *req.Location
+		fields["location"] = *req.Location
+	}
+	if req.Locale != nil {
+		fields["locale"] = *req.Locale
+	}
+	if req.DigestEnabled != nil {
+		fields["digest_opt_out"] = !*req.DigestEnabled
 	}
 
 	// 保存更新
-	if err := s.repo.Update(user); err != nil {
-		return nil, errors.New("更新用户信息失败")
+	if len(fields) > 0 {
+		if err := s.repo.UpdateFields(userID, fields); err != nil {
+			return nil, errors.New("更新用户信息失败")
+		}
 	}
 
 	return s.GetProfile(userID)
 }
+
+// UpdateRole 管理员修改用户角色，降级时使该用户已签发的令牌立即失效
+func (s *Service) UpdateRole(ctx context.Context, operatorID, userID uint, req *UpdateRoleRequest) (*ProfileResponse, error) {
+	if !models.IsAssignableRole(req.Role) {
+		return nil, ErrInvalidRole
+	}
+	// 避免管理员误操作导致系统中没有管理员
+	if operatorID == userID {
+		return nil, ErrCannotChangeOwnRole
+	}
+
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("获取用户信息失败")
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsGuest() {
+		return nil, ErrGuestAccount
+	}
+	if user.Role == req.Role {
+		return s.GetProfile(userID)
+	}
+
+	if err := s.repo.UpdateRole(userID, req.Role); err != nil {
+		return nil, errors.New("修改角色失败")
+	}
+
+	// 令牌中携带角色，降级后旧令牌不能继续使用原有权限
+	if !models.RoleAtLeast(req.Role, user.Role) {
+		if err := s.tokens.RevokeAllForUser(ctx, userID); err != nil {
+			return nil, errors.New("吊销用户令牌失败")
+		}
+	}
+
+	return s.GetProfile(userID)
+}
+
+// ChangePassword 确认身份后修改密码，并使所有设备上的令牌失效
+func (s *Service) ChangePassword(ctx context.Context, userID uint, ip string, req *ChangePasswordRequest) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return errors.New("获取用户信息失败")
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.IsGuest() {
+		return ErrGuestMustRegister
+	}
+	if err := verifyIdentity(ctx, s.codes, s.guard, user, identityProof{Password: req.CurrentPassword, Code: req.Code, IP: ip}); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.repo.UpdatePassword(userID, hashedPassword); err != nil {
+		return errors.New("修改密码失败")
+	}
+	if err := s.tokens.RevokeAllForUser(ctx, userID); err != nil {
+		return errors.New("吊销用户令牌失败")
+	}
+
+	go s.sendSecurityNotice(user, &email.SecurityAlertData{Event: email.AlertPasswordChanged})
+	return nil
+}
+
+// RequestEmailChange 确认身份后向新邮箱发送验证码，验证通过前邮箱不会变更
+func (s *Service) RequestEmailChange(ctx context.Context, userID uint, ip string, req *ChangeEmailRequest) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return errors.New("获取用户信息失败")
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.IsGuest() {
+		return ErrGuestMustRegister
+	}
+	if err := verifyIdentity(ctx, s.codes, s.guard, user, identityProof{Password: req.Password, Code: req.Code, IP: ip}); err != nil {
+		return err
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrEmailUnchanged
+	}
+	if taken, err := s.repo.EmailExists(newEmail); err != nil {
+		return errors.New("获取用户信息失败")
+	} else if taken {
+		return ErrEmailTaken
+	}
+
+	return s.codes.SendVerificationCodeTo(ctx, email.PurposeChangeEmail, changeEmailSubject(userID, newEmail), newEmail, user.Locale)
+}
+
+// ConfirmEmailChange 校验新邮箱的验证码后更换邮箱，
+// 使所有设备上的令牌失效，并通知原邮箱
+func (s *Service) ConfirmEmailChange(ctx context.Context, userID uint, req *ConfirmEmailRequest) error {
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return errors.New("获取用户信息失败")
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	// 验证码只对发起请求的用户和当时申请的新邮箱有效，
+	// 其他账号无法用自己收到的验证码把邮箱改到他人账号上
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if err := s.codes.VerifyCode(ctx, email.PurposeChangeEmail, changeEmailSubject(userID, newEmail), req.Code); err != nil {
+		return err
+	}
+	// 发送验证码后邮箱可能已被其他账号注册
+	if taken, err := s.repo.EmailExists(newEmail); err != nil {
+		return errors.New("获取用户信息失败")
+	} else if taken {
+		return ErrEmailTaken
+	}
+
+	if err := s.repo.UpdateEmail(userID, newEmail, time.Now()); err != nil {
+		return errors.New("更换邮箱失败")
+	}
+	if err := s.tokens.RevokeAllForUser(ctx, userID); err != nil {
+		return errors.New("吊销用户令牌失败")
+	}
+
+	// user 仍是更换前的信息，通知发往原邮箱
+	go s.sendSecurityNotice(user, &email.SecurityAlertData{Event: email.AlertEmailChanged, NewEmail: maskEmail(newEmail)})
+	return nil
+}
+
+// changeEmailSubject 更换邮箱验证码的存储键，记录待确认的变更：用户 ID → 新邮箱
+func changeEmailSubject(userID uint, newEmail string) string {
+	return fmt.Sprintf("user:%d:%s", userID, strings.ToLower(newEmail))
+}
+
+// sendSecurityNotice 使用用户的语言发送账号安全提醒
+func (s *Service) sendSecurityNotice(user *models.User, data *email.SecurityAlertData) {
+	data.Time = time.Now().Format("2006-01-02 15:04:05")
+	if err := s.sender.SendTemplate(user.Email, user.Locale, email.TemplateSecurityAlert, data); err != nil {
+		log.Printf("Failed to send security notice to %s: %v", user.Email, err)
+	}
+}
+
+// maskEmail 隐藏邮箱用户名的中间部分，如 ab***@example.com
+func maskEmail(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return address
+	}
+	name := address[:at]
+	if len(name) > 2 {
+		name = name[:2]
+	}
+	return name + "***" + address[at:]
+}
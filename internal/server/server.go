@@ -4,6 +4,7 @@ import (
 	"context"
 	"go-tree-hollow/configs"
 	"go-tree-hollow/internal/middleware"
+	"go-tree-hollow/internal/models"
 	"go-tree-hollow/internal/modules/auth"
 	"go-tree-hollow/internal/modules/chat"
 	"go-tree-hollow/internal/modules/email"
@@ -12,20 +13,23 @@ import (
 	"go-tree-hollow/internal/modules/upload"
 	"go-tree-hollow/internal/modules/user"
 	"go-tree-hollow/pkg/database"
+	"go-tree-hollow/pkg/kv"
+	"go-tree-hollow/pkg/utils"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	config      *configs.Config
-	db          *gorm.DB
-	router      *gin.Engine
-	redisClient *redis.Client
+	config        *configs.Config
+	db            *gorm.DB
+	router        *gin.Engine
+	store         kv.Store
+	mailTransport email.Transport
+	mailTemplates *email.Templates
 }
 
 func NewServer(config *configs.Config) (*Server, error) {
@@ -35,11 +39,27 @@ func NewServer(config *configs.Config) (*Server, error) {
 		return nil, err
 	}
 
-	redisClient, err := email.NewClient(&config.Redis)
+	// 验证码、登录限制等临时数据的存储（Redis 或进程内存）
+	store, err := kv.NewStore(config.KVBackend, &config.Redis)
 	if err != nil {
 		return nil, err
 	}
 
+	// 邮件发送方式（SMTP、本地文件或日志）
+	mailTransport, err := email.NewTransport((*email.EmailConfig)(&config.Email))
+	if err != nil {
+		return nil, err
+	}
+	mailTemplates, err := email.LoadTemplates(config.Email.TemplateDir, config.AppName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加载令牌签名密钥
+	if err := utils.LoadSigningKeys(); err != nil {
+		return nil, err
+	}
+
 	// 初始化Gin
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -47,10 +67,12 @@ func NewServer(config *configs.Config) (*Server, error) {
 	router.Use(middleware.Logger())
 
 	server := &Server{
-		config:      config,
-		db:          db,
-		router:      router,
-		redisClient: redisClient,
+		config:        config,
+		db:            db,
+		router:        router,
+		store:         store,
+		mailTransport: mailTransport,
+		mailTemplates: mailTemplates,
 	}
 
 	// 注册路由
@@ -63,28 +85,45 @@ func (s *Server) setupRoutes() {
 	// API v1路由组
 	v1 := s.router.Group("/api/v1")
 
-	// 认证模块
+	// 认证中间件
 	authRepo := auth.NewRepository(s.db)
-	authService := auth.NewService(authRepo)
+	tokenRepo := auth.NewTokenRepository(s.db, s.store, "app:auth")
+	tokenService := auth.NewTokenService(tokenRepo, authRepo, s.config)
+	authRequired := middleware.AuthRequired(tokenService)
+	optionalAuth := middleware.OptionalAuth(tokenService)
+
+	// 邮箱模块，邮件先写入发送队列，由后台任务发送和重试
+	mailQueue := email.NewQueue(email.NewQueueRepository(s.db), s.mailTransport, s.config.MailQueue)
+	go mailQueue.Run(context.Background())
+	emailSender := email.NewSender(s.config.Email.From, mailQueue, s.mailTemplates)
+	// 各用途的验证码按用途分开存储，互不通用
+	codeRepo := email.NewCodeRepository(s.store, "app:email")
+	emailService := email.NewEmailService(emailSender, codeRepo, s.config)
+	emailHandler := email.NewEmailHandler(emailService)
+	queueHandler := email.NewQueueHandler(mailQueue)
+	email.RegisterRoutes(v1, emailHandler, queueHandler, authRequired, middleware.RequirePermission(models.PermManageMail))
+
+	// 认证模块
+	loginGuard := auth.NewLoginGuard(s.store, "app:login", s.config.LoginGuard)
+	twoFactorService := auth.NewTwoFactorService(authRepo, loginGuard, s.store, "app:mfa", s.config.AppName)
+	oidcService := auth.NewOIDCService(authRepo, tokenService, s.store, "app:oidc", s.config.OIDCProviders)
+	authService := auth.NewService(authRepo, tokenService, loginGuard, twoFactorService, oidcService, emailService, emailSender)
 	authHandler := auth.NewHandler(authService)
-	auth.RegisterRoutes(v1, authHandler)
+	auth.RegisterRoutes(v1, authHandler, authRequired, optionalAuth)
+	auth.RegisterWellKnownRoutes(s.router, authHandler)
 
 	// 用户模块（需要认证）
 	userRepo := user.NewRepository(s.db)
-	userService := user.NewService(userRepo)
+	userService := user.NewService(userRepo, tokenService, loginGuard, emailService, emailSender)
 	userHandler := user.NewHandler(userService)
-	user.RegisterRoutes(v1, userHandler)
-
-	// 邮箱模块
-	emailSender := email.NewSender((*email.EmailConfig)(&s.config.Email))
-	emailRepo := email.NewCodeRepository(s.redisClient, "app:email")
-	emailService := email.NewEmailService(
-		emailSender,
-		emailRepo,
-		s.config,
-	)
-	emailHandler := email.NewEmailHandler(emailService)
-	email.RegisterRoutes(v1, emailHandler)
+	accountRepo := user.NewAccountRepository(s.db)
+	accountService := user.NewAccountService(accountRepo, userRepo, tokenService, loginGuard, emailService, s.config.Account)
+	go accountService.Run(context.Background()) // 数据导出和到期注销的后台任务
+	accountHandler := user.NewAccountHandler(accountService)
+	digestService := user.NewDigestService(user.NewDigestRepository(s.db), emailSender, s.config.Digest, s.config.PublicURL)
+	go digestService.Run(context.Background()) // 每周动态摘要的后台任务
+	digestHandler := user.NewDigestHandler(digestService)
+	user.RegisterRoutes(v1, userHandler, accountHandler, digestHandler, authRequired, middleware.RequirePermission(models.PermManageRoles))
 
 	// 点赞功能
 	likeRepo := post.NewLikeRepository(s.db)
@@ -93,15 +132,25 @@ func (s *Server) setupRoutes() {
 
 	// 内容模块 (需要认证)
 	postRepo := post.NewRepository(s.db)
-	postService := post.NewService(s.db, postRepo, likeRepo)
+	anonymizer := post.NewAnonymizer(s.config.AnonymousSecret)
+	postService := post.NewService(s.db, postRepo, likeRepo, anonymizer, s.config.Guest.PostTagIDs)
 	postHandler := post.NewHandler(postService)
 
 	// 评论功能
 	commentRepo := post.NewCommentRepository(s.db)
-	commentService := post.NewCommentService(commentRepo)
+	commentService := post.NewCommentService(commentRepo, postRepo, anonymizer)
 	commentHandler := post.NewCommentHandler(commentService)
 
-	post.Routes(v1, postHandler, likeHandler, commentHandler, middleware.AuthRequired(), middleware.OptionalAuth())
+	// 帖子搜索
+	searchService := post.NewSearchService(post.NewSearchRepository(s.db), likeRepo, anonymizer)
+	searchHandler := post.NewSearchHandler(searchService)
+
+	// 热门榜
+	trendingService := post.NewTrendingService(post.NewTrendingRepository(s.db), likeRepo, anonymizer, s.config.Trending)
+	go trendingService.Run(context.Background()) // 增量更新帖子热度的后台任务
+	trendingHandler := post.NewTrendingHandler(trendingService)
+
+	post.Routes(v1, postHandler, likeHandler, commentHandler, searchHandler, trendingHandler, authRequired, optionalAuth, middleware.RequirePermission(models.PermModerateContent))
 
 	// 标签模块
 	tagRepo := tag.NewRepository(s.db)
@@ -111,7 +160,7 @@ func (s *Server) setupRoutes() {
 
 	// 文件上传模块 (需要认证)
 	uploadHandler := upload.NewHandler()
-	upload.Routes(v1, uploadHandler, middleware.AuthRequired())
+	upload.Routes(v1, uploadHandler, authRequired)
 
 	// 聊天模块 (需要认证)
 	chatRepo := chat.NewRepository(s.db)
@@ -119,7 +168,7 @@ func (s *Server) setupRoutes() {
 	chatHub := chat.NewHub(chatService)
 	go chatHub.Run() // Start WebSocket hub in background
 	chatHandler := chat.NewHandler(chatService, chatHub)
-	chat.RegisterRoutes(v1, chatHandler)
+	chat.RegisterRoutes(v1, chatHandler, authRequired, middleware.RequirePermission(models.PermUseChat))
 
 	// 提供静态文件访问
 	s.router.Static("/uploads", "./uploads")
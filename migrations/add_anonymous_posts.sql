@@ -0,0 +1,2 @@
+-- 匿名发帖：公开响应中隐藏作者，以按帖子生成的化名代替
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS anonymous BOOLEAN NOT NULL DEFAULT FALSE;
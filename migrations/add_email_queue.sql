@@ -0,0 +1,23 @@
+-- 邮件发送队列 (PostgreSQL)
+-- 发送成功的邮件直接删除，多次失败的邮件标记为 dead 留待排查
+
+CREATE TABLE IF NOT EXISTS queued_emails (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    "from" VARCHAR(255) NOT NULL,
+    "to" VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    html_body TEXT,
+    text_body TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    last_error VARCHAR(1024)
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_emails_status ON queued_emails(status);
+CREATE INDEX IF NOT EXISTS idx_queued_emails_next_attempt_at ON queued_emails(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_queued_emails_deleted_at ON queued_emails(deleted_at);
@@ -0,0 +1,2 @@
+-- 邮件队列保存额外的邮件头（JSON 对象），如动态摘要的 List-Unsubscribe
+ALTER TABLE queued_emails ADD COLUMN IF NOT EXISTS headers TEXT;
@@ -0,0 +1,2 @@
+-- 记录用户邮箱验证通过的时间
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
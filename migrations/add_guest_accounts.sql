@@ -0,0 +1,3 @@
+-- 游客账号：未注册的设备账号，注册后升级为普通用户并清空设备绑定
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guest_device_hash VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_guest_device_hash ON users(guest_device_hash);
@@ -0,0 +1,7 @@
+-- 游标分页索引 (PostgreSQL)
+-- 帖子流、评论和私信历史按 (created_at, id) 倒序分页，索引与排序一致时无需排序和 OFFSET 扫描
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_tag_id_created_at_id ON posts (tag_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at_id ON comments (post_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_sender_receiver_created_at_id ON messages (sender_id, receiver_id, created_at DESC, id DESC);
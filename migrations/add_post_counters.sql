@@ -0,0 +1,14 @@
+-- 帖子的点赞数和评论数计数列，随点赞和评论在同一事务中更新，列表页不再逐条统计
+-- 计数与明细不一致时可运行 go run ./cmd/admin recount-posts 重新计算
+
+-- 点赞切换依赖 (user_id, post_id) 唯一，避免并发请求重复点赞使计数多加
+-- 先清理历史上可能存在的重复点赞，只保留最早的一条
+DELETE FROM likes a USING likes b WHERE a.user_id = b.user_id AND a.post_id = b.post_id AND a.id > b.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_user_post ON likes (user_id, post_id);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS likes_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_count BIGINT NOT NULL DEFAULT 0;
+
+UPDATE posts SET
+    likes_count = (SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id AND likes.deleted_at IS NULL),
+    comments_count = (SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL);
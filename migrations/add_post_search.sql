@@ -0,0 +1,9 @@
+-- 帖子全文检索 (PostgreSQL)
+-- search_tokens 由应用切分正文后写入（汉字二元切分，见 pkg/segment），
+-- search_vector 由其自动生成并建立 GIN 索引。
+-- 已有帖子需在迁移后运行 go run ./cmd/admin reindex-posts 生成词元。
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_tokens TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', search_tokens)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
@@ -0,0 +1,11 @@
+-- 热门榜热度分数 (PostgreSQL)
+-- 每个帖子在每个统计窗口（如 24h、7d）各一行，由后台任务根据点赞、评论和收藏增量更新
+CREATE TABLE IF NOT EXISTS post_scores (
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    period VARCHAR(16) NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (post_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_scores_period_score ON post_scores (period, score DESC);
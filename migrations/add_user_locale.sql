@@ -0,0 +1,2 @@
+-- 用户语言：邮件等通知按该语言发送，为空时使用默认语言（zh-CN）
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(10);
@@ -0,0 +1,3 @@
+-- 每周动态摘要：用户可退订，digest_sent_at 记录上一次摘要统计到的时间，避免重复发送
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,22 @@
+-- 帖子全文检索 (SQLite)
+-- 使用 FTS5 外部内容表索引 posts.search_tokens，由触发器与帖子表保持同步。
+-- FTS5 需要以 sqlite_fts5 构建标签编译（make build 或 go build -tags sqlite_fts5 ./cmd/server），未启用时服务启动会报错
+-- 已有帖子需在迁移后运行 go run -tags sqlite_fts5 ./cmd/admin reindex-posts 生成词元。
+ALTER TABLE posts ADD COLUMN search_tokens TEXT NOT NULL DEFAULT '';
+
+CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(search_tokens, content='posts', content_rowid='id');
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_ai AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts(rowid, search_tokens) VALUES (new.id, new.search_tokens);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_ad AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, search_tokens) VALUES ('delete', old.id, old.search_tokens);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_au AFTER UPDATE OF search_tokens ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, search_tokens) VALUES ('delete', old.id, old.search_tokens);
+    INSERT INTO posts_fts(rowid, search_tokens) VALUES (new.id, new.search_tokens);
+END;
+
+INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -14,7 +15,7 @@ func NewDB(dsn string) (*gorm.DB, error) {
 	var err error
 
 	// 根据DSN前缀判断数据库类型
-	if dsn[:8] == "postgres" {
+	if strings.HasPrefix(dsn, "postgres") {
 		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	} else {
 		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
@@ -24,5 +25,24 @@ func NewDB(dsn string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
+	if db.Dialector.Name() == "sqlite" {
+		if err := checkSQLiteFTS5(db); err != nil {
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
+
+// checkSQLiteFTS5 确认 SQLite 驱动编译时启用了 FTS5。
+// 帖子全文检索的触发器依赖 FTS5，缺少时所有帖子写入都会失败，因此在启动时直接报错。
+func checkSQLiteFTS5(db *gorm.DB) error {
+	var enabled int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return fmt.Errorf("failed to check sqlite fts5 support: %w", err)
+	}
+	if enabled != 1 {
+		return fmt.Errorf("SQLite 驱动未启用 FTS5，请使用 -tags sqlite_fts5 编译（make build / make run）")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval 清理过期键的最短间隔
+const memorySweepInterval = time.Minute
+
+// MemoryStore 进程内的存储，过期的键在访问时或定期写入时清理
+type MemoryStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	value     string
+	expiresAt time.Time // 零值表示永不过期
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.get(key, time.Now())
+	if !ok {
+		return "", ErrNotFound
+	}
+	return item.value, nil
+}
+
+func (s *MemoryStore) GetDel(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.get(key, time.Now())
+	if !ok {
+		return "", ErrNotFound
+	}
+	delete(s.items, key)
+	return item.value, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.set(key, value, ttl, now)
+	s.sweep(now)
+	return nil
+}
+
+func (s *MemoryStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.get(key, now); ok {
+		return false, nil
+	}
+	s.set(key, value, ttl, now)
+	s.sweep(now)
+	return true, nil
+}
+
+func (s *MemoryStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+	for _, key := range keys {
+		if _, ok := s.get(key, now); ok {
+			delete(s.items, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.get(key, time.Now())
+	return ok, nil
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	item, ok := s.get(key, now)
+	if !ok {
+		s.set(key, "1", ttl, now)
+		s.sweep(now)
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("kv: value of %s is not an integer", key)
+	}
+	n++
+	item.value = strconv.FormatInt(n, 10)
+	s.items[key] = item
+	return n, nil
+}
+
+func (s *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	item, ok := s.get(key, now)
+	if !ok || item.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return item.expiresAt.Sub(now), nil
+}
+
+// get 获取未过期的键，已过期的键顺便删除，调用方需持有锁
+func (s *MemoryStore) get(key string, now time.Time) (memoryItem, bool) {
+	item, ok := s.items[key]
+	if !ok {
+		return memoryItem{}, false
+	}
+	if item.expired(now) {
+		delete(s.items, key)
+		return memoryItem{}, false
+	}
+	return item, true
+}
+
+func (s *MemoryStore) set(key, value string, ttl time.Duration, now time.Time) {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+	s.items[key] = item
+}
+
+// sweep 删除所有过期的键，避免从不再访问的键一直占用内存，调用方需持有锁
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, item := range s.items {
+		if item.expired(now) {
+			delete(s.items, key)
+		}
+	}
+}
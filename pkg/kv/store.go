@@ -0,0 +1,45 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-tree-hollow/configs"
+)
+
+// ErrNotFound 键不存在或已过期
+var ErrNotFound = errors.New("kv: key not found")
+
+// Store 带过期时间的键值存储，用于验证码、登录限制、令牌黑名单等临时数据。
+// ttl 为 0 表示永不过期。
+type Store interface {
+	// Get 获取值，键不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (string, error)
+	// GetDel 获取值并删除，键不存在时返回 ErrNotFound
+	GetDel(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX 仅在键不存在时设置，返回是否设置成功
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Del 删除键，返回实际删除的数量
+	Del(ctx context.Context, keys ...string) (int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Incr 将计数器加一并返回新值，计数器由本次调用创建时设置过期时间
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// TTL 返回剩余的过期时间，键不存在或永不过期时返回 0
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// NewStore 根据配置创建存储：redis（默认）或 memory。
+// memory 仅在当前进程内有效，重启后数据清空，多实例部署时各实例互不共享，适用于本地开发和单机部署。
+func NewStore(backend string, redisCfg *configs.RedisConfig) (Store, error) {
+	switch backend {
+	case "", "redis":
+		return NewRedisStore(redisCfg)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", backend)
+	}
+}
@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor 客户端传入的游标无法解析
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// Cursor 键集分页的位置，指向上一页的最后一条记录。
+// 列表按 (created_at, id) 倒序排列，id 用于区分创建时间相同的记录。
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode 生成指向该记录之后的游标，对客户端不透明
+func Encode(createdAt time.Time, id uint) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode 解析客户端传入的游标，空字符串表示第一页，返回 nil
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	ns, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, ns), ID: uint(n)}, nil
+}
+
+// Scope 按 (created_at, id) 倒序排列，并只保留游标之后的记录，cursor 为 nil 时从头开始
+func Scope(cursor *Cursor) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if cursor != nil {
+			db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		}
+		return db.Order("created_at DESC").Order("id DESC")
+	}
+}
+
+// Next 处理多查询一条的结果：items 最多取 limit+1 条，超出 limit 说明还有下一页，
+// 截去多出的一条并返回指向本页最后一条的游标；没有下一页时返回空字符串
+func Next[T any](items []T, limit int, key func(T) (time.Time, uint)) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	createdAt, id := key(items[limit-1])
+	return items, Encode(createdAt, id)
+}
@@ -0,0 +1,91 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		id        uint
+	}{
+		{"纳秒精度", time.Date(2026, 10, 16, 8, 30, 0, 123456789, time.UTC), 42},
+		{"id 为 0", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{"id 上限", time.Date(2026, 1, 1, 0, 0, 0, 1, time.UTC), 1<<32 - 1},
+		{"纪元之前", time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC), 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := Decode(Encode(tt.createdAt, tt.id))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !cursor.CreatedAt.Equal(tt.createdAt) || cursor.ID != tt.id {
+				t.Errorf("got (%v, %d), want (%v, %d)", cursor.CreatedAt, cursor.ID, tt.createdAt, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	cursor, err := Decode("")
+	if cursor != nil || err != nil {
+		t.Errorf("Decode(\"\") = (%v, %v), want (nil, nil)", cursor, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"不是 base64", "!!!"},
+		{"缺少分隔符", encode("1700000000")},
+		{"时间不是数字", encode("abc:1")},
+		{"id 不是数字", encode("1700000000:abc")},
+		{"id 为负数", encode("1700000000:-1")},
+		{"id 超出范围", encode("1700000000:4294967296")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	items := []uint{5, 4, 3}
+	key := func(id uint) (time.Time, uint) {
+		return base.Add(time.Duration(id) * time.Second), id
+	}
+
+	tests := []struct {
+		name       string
+		items      []uint
+		limit      int
+		wantLen    int
+		wantCursor string
+	}{
+		{"少于一页", items[:2], 2, 2, ""},
+		{"多出一条", items, 2, 2, Encode(key(4))},
+		{"空列表", nil, 2, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, cursor := Next(tt.items, tt.limit, key)
+			if len(page) != tt.wantLen || cursor != tt.wantCursor {
+				t.Errorf("Next = (%v, %q), want %d items and %q", page, cursor, tt.wantLen, tt.wantCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// segment 为帖子全文检索切分词元。
+//
+// 中文没有空格分词，这里采用全文检索常用的二元切分：连续的汉字按相邻两字切成词元，
+// 如“考试焦虑”切分为“考试 试焦 焦虑”。索引时额外保留单字，使单字查询也能命中；
+// 字母和数字按连续的串切分并转为小写，标点和空白作为分隔符丢弃。
+package segment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Index 将文本切分为用于建立索引的词元，以空格连接
+func Index(text string) string {
+	var tokens []string
+	for _, run := range runs(text) {
+		if !run.cjk {
+			tokens = append(tokens, run.text)
+			continue
+		}
+		chars := []rune(run.text)
+		for i := range chars {
+			tokens = append(tokens, string(chars[i]))
+			if i+1 < len(chars) {
+				tokens = append(tokens, string(chars[i:i+2]))
+			}
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Query 将查询语句切分为词元，文档需包含全部词元才算匹配。
+// 汉字串只取二元词元，只有一个字时取单字；结果已去重。
+func Query(q string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, run := range runs(q) {
+		chars := []rune(run.text)
+		if !run.cjk || len(chars) == 1 {
+			add(run.text)
+			continue
+		}
+		for i := 0; i+1 < len(chars); i++ {
+			add(string(chars[i : i+2]))
+		}
+	}
+	return tokens
+}
+
+type run struct {
+	text string
+	cjk  bool
+}
+
+// runs 将文本拆分为连续的汉字串和字母数字串
+func runs(text string) []run {
+	var result []run
+	var b strings.Builder
+	cjk := false
+
+	flush := func() {
+		if b.Len() > 0 {
+			result = append(result, run{text: b.String(), cjk: cjk})
+			b.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case IsCJK(r):
+			if !cjk {
+				flush()
+				cjk = true
+			}
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+				cjk = false
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// IsCJK 判断字符是否按汉字方式切分（包括日文假名和韩文）
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
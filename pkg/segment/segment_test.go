@@ -0,0 +1,73 @@
+package segment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"空文本", "", ""},
+		{"单字", "考", "考"},
+		{"汉字串保留单字和二元词元", "考试焦虑", "考 考试 试 试焦 焦 焦虑 虑"},
+		{"字母数字转小写", "Go 1.25", "go 1 25"},
+		{"汉字与字母相邻时分开", "学Go语言", "学 go 语 语言 言"},
+		{"标点作为分隔符", "考试，焦虑！", "考 考试 试 焦 焦虑 虑"},
+		{"日文假名", "ありがとう", "あ あり り りが が がと と とう う"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Index(tt.text); got != tt.want {
+				t.Errorf("Index(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want []string
+	}{
+		{"空查询", "", nil},
+		{"只有标点", "，。！？", nil},
+		{"单字", "考", []string{"考"}},
+		{"汉字串只取二元词元", "考试焦虑", []string{"考试", "试焦", "焦虑"}},
+		{"多个关键词", "考试 焦虑", []string{"考试", "焦虑"}},
+		{"去重", "焦虑焦虑", []string{"焦虑", "虑焦"}},
+		{"重复关键词去重", "GO go", []string{"go"}},
+		{"汉字与字母混合", "学Go", []string{"学", "go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Query(tt.q); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCJK(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want bool
+	}{
+		{'中', true},
+		{'あ', true},
+		{'カ', true},
+		{'한', true},
+		{'a', false},
+		{'1', false},
+		{'，', false},
+	}
+	for _, tt := range tests {
+		if got := IsCJK(tt.r); got != tt.want {
+			t.Errorf("IsCJK(%q) = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+}
@@ -1,18 +1,21 @@
 package utils
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"math/big"
 )
 
-// GenerateCode 生成指定长度的数字验证码
-func GenerateCode(length int) string {
-	rand.New(rand.NewSource(time.Now().UnixNano() + rand.Int63()))
-
+// GenerateCode 生成指定长度的数字验证码，每一位都取自 crypto/rand
+func GenerateCode(length int) (string, error) {
 	const digits = "0123456789"
+	max := big.NewInt(int64(len(digits)))
 	code := make([]byte, length)
 	for i := range code {
-		code[i] = digits[rand.Intn(len(digits))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = digits[n.Int64()]
 	}
-	return string(code)
+	return string(code), nil
 }
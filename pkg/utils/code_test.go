@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestGenerateCode(t *testing.T) {
+	for _, length := range []int{4, 6, 8} {
+		code, err := GenerateCode(length)
+		if err != nil {
+			t.Fatalf("GenerateCode(%d): %v", length, err)
+		}
+		if len(code) != length {
+			t.Errorf("GenerateCode(%d) = %q, want %d digits", length, code, length)
+		}
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				t.Errorf("GenerateCode(%d) = %q, contains non-digit %q", length, code, c)
+			}
+		}
+	}
+
+	// 每一位都应能取到 0-9 的所有数字
+	seen := make(map[rune]bool)
+	for i := 0; i < 200 && len(seen) < 10; i++ {
+		code, _ := GenerateCode(6)
+		for _, c := range code {
+			seen[c] = true
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("GenerateCode produced only %d distinct digits", len(seen))
+	}
+}
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DeviceFingerprint 计算设备标识的哈希，数据库和令牌中只保存哈希，不保存原始设备标识
+func DeviceFingerprint(deviceID string) string {
+	sum := sha256.Sum256([]byte(deviceID))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,69 +2,126 @@ package utils
 
 import (
 	"fmt"
+	"log"
 	"sync" // 必须引入 sync 包
 	"time"
 
 	"go-tree-hollow/configs"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims JWT载荷结构
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role,omitempty"` // 签发时的用户角色，角色变更后需重新签发才会生效
+	SessionID uint   `json:"sid,omitempty"`  // 令牌所属的登录会话
+	DeviceID  string `json:"did,omitempty"`  // 游客令牌绑定的设备（设备标识的哈希），请求时需携带对应的 X-Device-ID
 	jwt.RegisteredClaims
 }
 
 // 定义全局变量，确保在包级别可见
 var (
-	jwtSecret     []byte
-	jwtExpireDays int
-	once          sync.Once
+	jwtKeys      map[string]*signingKey // kid -> 密钥，包含仍需用于验证的退役密钥
+	jwtActiveKey *signingKey            // 当前用于签发令牌的密钥
+	jwtKeysErr   error
+	jwtIssuer    string
+	jwtAccessTTL time.Duration
+	once         sync.Once
 )
 
+func init() {
+	// 令牌中的时间精确到毫秒：吊销时间点同样以毫秒记录，
+	// 同一秒内先吊销、后重新登录签发的令牌不会被误判为已吊销
+	jwt.TimePrecision = time.Millisecond
+}
+
 // setupConfig 内部辅助函数：确保配置只被安全加载一次
 // GenerateToken 和 ParseToken 都要调用它，防止谁先谁后的问题
 func setupConfig() {
 	once.Do(func() {
 		config := configs.LoadConfig()
-		jwtSecret = []byte(config.JWTSecret)
-		jwtExpireDays = config.JWTExpireDays
+		jwtIssuer = config.JWTIssuer
+		jwtAccessTTL = time.Duration(config.JWTAccessMinutes) * time.Minute
+
+		if config.JWTKeysDir == "" {
+			log.Println("警告：未配置 JWT_KEYS_DIR，使用临时签名密钥，重启后已签发的令牌将全部失效")
+			jwtActiveKey, jwtKeysErr = generateEphemeralKey()
+			if jwtKeysErr == nil {
+				jwtKeys = map[string]*signingKey{jwtActiveKey.ID: jwtActiveKey}
+			}
+			return
+		}
+		jwtKeys, jwtActiveKey, jwtKeysErr = loadSigningKeys(config.JWTKeysDir, config.JWTActiveKeyID)
 	})
 }
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, email string) (string, error) {
+// LoadSigningKeys 加载签名密钥，启动时调用以便尽早发现密钥配置错误
+func LoadSigningKeys() error {
+	setupConfig()
+	return jwtKeysErr
+}
+
+// AccessTokenTTL 返回访问令牌的有效期
+func AccessTokenTTL() time.Duration {
+	setupConfig()
+	return jwtAccessTTL
+}
+
+// GenerateToken 生成短期有效的JWT访问令牌，每个令牌带有唯一的 jti 以便吊销
+// deviceID 非空时令牌只能在对应设备上使用
+func GenerateToken(userID uint, email, role string, sessionID uint, deviceID string) (string, error) {
 	// 1. 初始化配置 (只会执行一次)
 	setupConfig()
+	if jwtKeysErr != nil {
+		return "", jwtKeysErr
+	}
 
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		DeviceID:  deviceID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			// 2. 这里必须使用全局变量 jwtExpireDays，而不是局部的 config
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour * time.Duration(jwtExpireDays))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:     uuid.New().String(),
+			Issuer: jwtIssuer,
+			// 2. 这里必须使用全局变量 jwtAccessTTL，而不是局部的 config
+			ExpiresAt: jwt.NewNumericDate(now.Add(jwtAccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	// 3. 使用当前密钥签名，并在头部写入 kid 供验证方选择公钥
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = jwtActiveKey.ID
+	return token.SignedString(jwtActiveKey.PrivateKey)
 }
 
 // ParseToken 解析JWT令牌
 func ParseToken(tokenString string) (*Claims, error) {
-	// 3. 解析时也要调用 setupConfig，替代原来非线程安全的 if jwtSecret == nil
+	// 4. 解析时也要调用 setupConfig，保证密钥已加载
 	setupConfig()
+	if jwtKeysErr != nil {
+		return nil, jwtKeysErr
+	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
-	})
+		// 按 kid 选择公钥，退役密钥在删除前仍可验证其签发的令牌
+		kid, _ := token.Header["kid"].(string)
+		key, ok := jwtKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		return key.PublicKey, nil
+	}, jwt.WithIssuer(jwtIssuer))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
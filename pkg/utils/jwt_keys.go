@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// signingKey 一把 Ed25519 签名密钥，PrivateKey 为空表示只用于验证的退役密钥
+type signingKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// JSONWebKey JWKS 中的一把公钥（RFC 8037 OKP 格式）
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JSONWebKeySet 对外发布的公钥集合
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// loadSigningKeys 从目录加载所有 <kid>.pem 密钥，并返回 activeID 对应的签名密钥
+func loadSigningKeys(dir, activeID string) (map[string]*signingKey, *signingKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make(map[string]*signingKey, len(paths))
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载签名密钥 %s 失败: %w", kid, err)
+		}
+		key.ID = kid
+		keys[kid] = key
+	}
+
+	active, ok := keys[activeID]
+	if !ok {
+		return nil, nil, fmt.Errorf("签名密钥 %q 不存在于 %s", activeID, dir)
+	}
+	if active.PrivateKey == nil {
+		return nil, nil, fmt.Errorf("签名密钥 %q 缺少私钥，无法用于签发令牌", activeID)
+	}
+	return keys, active, nil
+}
+
+// readSigningKey 读取 PKCS#8 私钥或 PKIX 公钥格式的 Ed25519 密钥
+func readSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("不是有效的 PEM 文件")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		priv, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("仅支持 Ed25519 密钥")
+		}
+		return &signingKey{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}, nil
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("仅支持 Ed25519 密钥")
+		}
+		return &signingKey{PublicKey: pub}, nil
+	default:
+		return nil, fmt.Errorf("不支持的 PEM 类型 %q", block.Type)
+	}
+}
+
+// generateEphemeralKey 生成仅存在于内存中的临时密钥，用于未配置密钥目录的开发环境
+func generateEphemeralKey() (*signingKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{ID: "ephemeral", PrivateKey: priv, PublicKey: pub}, nil
+}
+
+// JWKS 返回当前所有可用于验证令牌的公钥，供其他服务校验本服务签发的令牌
+func JWKS() (*JSONWebKeySet, error) {
+	setupConfig()
+	if jwtKeysErr != nil {
+		return nil, jwtKeysErr
+	}
+
+	set := &JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(jwtKeys))}
+	for _, key := range jwtKeys {
+		set.Keys = append(set.Keys, JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey),
+			Kid: key.ID,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试密钥 "12345678901234567890"
+var totpTestSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	// RFC 6238 给出的是 8 位验证码，这里取后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		offset int64 // 验证码所在时间步相对当前时间步的偏移
+		wantOK bool
+	}{
+		{"当前窗口", 0, true},
+		{"前一个窗口", -1, true},
+		{"后一个窗口", 1, true},
+		{"超出前偏差", -2, false},
+		{"超出后偏差", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := totpCode(key, current+tt.offset)
+			step, ok := ValidateTOTP(totpTestSecret, code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("ValidateTOTP step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInvalidInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"密钥不是 Base32", "not-base32!", "287082"},
+		{"验证码位数不足", totpTestSecret, "28708"},
+		{"验证码位数过多", totpTestSecret, "2870820"},
+		{"验证码错误", totpTestSecret, "000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+				t.Errorf("ValidateTOTP(%q, %q) ok = true, want false", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPNormalizesSecret(t *testing.T) {
+	secret := " " + strings.ToLower(totpTestSecret) + " "
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59, 0)); !ok {
+		t.Error("ValidateTOTP should accept lowercase secrets with surrounding spaces")
+	}
+}